@@ -0,0 +1,424 @@
+package draupnir
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MIMETextXML is the legacy XML content type, accepted alongside MIMEApplicationXML.
+const MIMETextXML = "text/xml"
+
+// maxBindIndex bounds the slice indices accepted by the form binder, so that a key such
+// as items[999999999].name cannot allocate a huge slice.
+const maxBindIndex = 1000
+
+var (
+	fileHeaderType      = reflect.TypeOf(multipart.FileHeader{})
+	fileHeaderPtrType   = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// Bind decodes the request into obj, choosing the decoder from the Content-Type header:
+// JSON, XML, an urlencoded form or a multipart form. Requests without a body are bound
+// from the query string. Malformed input is reported as a 400 *HTTPError and an
+// unsupported content type as 415. Like every Bind method, it then validates obj.
+func (c *Context) Bind(obj any) error {
+	var err error
+	switch mediaType := c.mediaType(); {
+	case mediaType == "" && c.Request.ContentLength == 0:
+		err = c.bindQuery(obj)
+	case isJSON(mediaType):
+		err = c.decodeJSON(obj)
+	case isXML(mediaType):
+		err = c.decodeXML(obj)
+	case mediaType == MIMEApplicationForm, mediaType == MIMEMultipartForm:
+		err = c.bindForm(obj)
+	default:
+		err = NewHTTPError(http.StatusUnsupportedMediaType, "")
+	}
+	return c.validateBound(obj, err)
+}
+
+// validateBound validates obj once it was decoded without error.
+func (c *Context) validateBound(obj any, err error) error {
+	if err != nil {
+		return err
+	}
+	return c.Validate(obj)
+}
+
+// BindXML binds XML body to a struct
+func (c *Context) BindXML(obj any) error {
+	if !isXML(c.mediaType()) {
+		return unsupportedMediaType(MIMEApplicationXML)
+	}
+	return c.validateBound(obj, c.decodeXML(obj))
+}
+
+// decodeXML decodes XML body to a struct
+func (c *Context) decodeXML(obj any) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return bodyError("failed to read request body", err)
+	}
+	defer c.Request.Body.Close()
+
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	if err := xml.Unmarshal(body, obj); err != nil {
+		return bodyError("invalid XML body", err)
+	}
+	return nil
+}
+
+// BindForm binds an urlencoded or multipart form to a struct, using the `form` tag.
+// Nested fields are addressed as user.name and slice elements as items[0].name, and
+// *multipart.FileHeader fields receive the uploaded files of their key.
+func (c *Context) BindForm(obj any) error {
+	if mediaType := c.mediaType(); mediaType != MIMEApplicationForm && mediaType != MIMEMultipartForm {
+		return unsupportedMediaType(MIMEApplicationForm + " or " + MIMEMultipartForm)
+	}
+	return c.validateBound(obj, c.bindForm(obj))
+}
+
+func (c *Context) bindForm(obj any) error {
+	if err := c.parseForm(); err != nil {
+		return bodyError("invalid form body", err)
+	}
+	b := &binder{tags: []string{"form"}, values: c.formValues}
+	if c.multipartForm != nil {
+		b.files = c.multipartForm.File
+	}
+	return b.bind(obj)
+}
+
+// BindQuery binds the query string to a struct, using the `query` tag, or the `form`
+// tag for fields without one.
+func (c *Context) BindQuery(obj any) error {
+	return c.validateBound(obj, c.bindQuery(obj))
+}
+
+func (c *Context) bindQuery(obj any) error {
+	b := &binder{tags: []string{"query", "form"}, values: c.GetQueries()}
+	return b.bind(obj)
+}
+
+// BindHeader binds request headers to a struct, using the `header` tag.
+// Header names are matched case-insensitively.
+func (c *Context) BindHeader(obj any) error {
+	b := &binder{tags: []string{"header"}, values: c.Request.Header, header: true}
+	return c.validateBound(obj, b.bind(obj))
+}
+
+// BindURI binds path parameters to a struct, using the `uri` tag.
+func (c *Context) BindURI(obj any) error {
+	values := make(map[string][]string, len(c.params))
+	for _, p := range c.params {
+		values[p.Key] = []string{p.Value}
+	}
+	b := &binder{tags: []string{"uri"}, values: values}
+	return c.validateBound(obj, b.bind(obj))
+}
+
+// mediaType returns the media type of the request body, without parameters.
+func (c *Context) mediaType() string {
+	contentType := c.Request.Header.Get(HeaderContentType)
+	if contentType == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+func isXML(mediaType string) bool {
+	return mediaType == MIMEApplicationXML || mediaType == MIMETextXML || strings.HasSuffix(mediaType, "+xml")
+}
+
+func unsupportedMediaType(want string) error {
+	return NewHTTPError(http.StatusUnsupportedMediaType, "expected Content-Type "+want)
+}
+
+// binder sets struct fields from request values keyed by the name in their tag.
+type binder struct {
+	tags   []string                           // tags naming the fields, the first present wins
+	values map[string][]string                // values by key
+	files  map[string][]*multipart.FileHeader // uploaded files by key, for multipart forms
+	header bool                               // keys are header names, matched canonically
+}
+
+func (b *binder) bind(obj any) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("draupnir: bind target must be a non-nil pointer to a struct, got %T", obj)
+	}
+	return b.bindStruct(v.Elem(), "")
+}
+
+func (b *binder) bindStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, tagged := b.fieldName(f)
+		if name == "-" {
+			continue
+		}
+		// The fields of embedded structs are promoted, even when the struct type is unexported.
+		if f.Anonymous && !tagged && f.Type.Kind() == reflect.Struct {
+			if err := b.bindStruct(v.Field(i), prefix); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if err := b.bindField(v.Field(i), prefix+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldName returns the key of a field from the first of the binder's tags it has,
+// or the field name when it has none.
+func (b *binder) fieldName(f reflect.StructField) (string, bool) {
+	for _, tag := range b.tags {
+		if name, ok := f.Tag.Lookup(tag); ok {
+			name, _, _ = strings.Cut(name, ",")
+			if name != "" {
+				return name, true
+			}
+		}
+	}
+	return f.Name, false
+}
+
+func (b *binder) bindField(v reflect.Value, key string) error {
+	switch v.Type() {
+	case fileHeaderPtrType:
+		if files := b.files[key]; len(files) > 0 {
+			v.Set(reflect.ValueOf(files[0]))
+		}
+		return nil
+	case fileHeaderType:
+		if files := b.files[key]; len(files) > 0 {
+			v.Set(reflect.ValueOf(*files[0]))
+		}
+		return nil
+	case fileHeaderSliceType:
+		if files := b.files[key]; len(files) > 0 {
+			v.Set(reflect.ValueOf(files))
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if !b.has(key) {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return b.bindField(v.Elem(), key)
+	}
+
+	if isScalar(v.Type()) {
+		if values := b.lookup(key); len(values) > 0 {
+			return b.setScalar(v, key, values[0])
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return b.bindStruct(v, key+".")
+	case reflect.Slice:
+		return b.bindSlice(v, key)
+	}
+	// Maps, channels and functions cannot be bound from values and are left untouched.
+	return nil
+}
+
+// bindSlice binds repeated values, as in tags=a&tags=b or tags[]=a, or indexed
+// keys, as in tags[0]=a or items[0].name=a.
+func (b *binder) bindSlice(v reflect.Value, key string) error {
+	elem := v.Type().Elem()
+	if isScalar(elem) {
+		values := b.lookup(key)
+		if len(values) == 0 {
+			values = b.lookup(key + "[]")
+		}
+		if len(values) > 0 {
+			s := reflect.MakeSlice(v.Type(), len(values), len(values))
+			for i, value := range values {
+				if err := b.setScalar(s.Index(i), key, value); err != nil {
+					return err
+				}
+			}
+			v.Set(s)
+			return nil
+		}
+	}
+
+	n, err := b.indexCount(key)
+	if err != nil || n == 0 {
+		return err
+	}
+	s := reflect.MakeSlice(v.Type(), n, n)
+	for i := range n {
+		if err := b.bindField(s.Index(i), key+"["+strconv.Itoa(i)+"]"); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+// indexCount returns one more than the highest index of key[i] among the keys.
+func (b *binder) indexCount(key string) (int, error) {
+	n := 0
+	scan := func(k string) error {
+		rest, ok := strings.CutPrefix(k, key+"[")
+		if !ok {
+			return nil
+		}
+		index, _, ok := strings.Cut(rest, "]")
+		if !ok || index == "" {
+			return nil
+		}
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= maxBindIndex {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid index in %q", k))
+		}
+		n = max(n, i+1)
+		return nil
+	}
+	for k := range b.values {
+		if err := scan(k); err != nil {
+			return 0, err
+		}
+	}
+	for k := range b.files {
+		if err := scan(k); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// has reports whether any value or file is keyed by key or by a key nested in it.
+func (b *binder) has(key string) bool {
+	if len(b.lookup(key)) > 0 || len(b.files[key]) > 0 {
+		return true
+	}
+	if b.header {
+		return false
+	}
+	nested := func(k string) bool {
+		return strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[")
+	}
+	for k := range b.values {
+		if nested(k) {
+			return true
+		}
+	}
+	for k := range b.files {
+		if nested(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *binder) lookup(key string) []string {
+	if b.header {
+		key = textproto.CanonicalMIMEHeaderKey(key)
+	}
+	return b.values[key]
+}
+
+// setScalar parses value into v. Empty values leave non-string fields unchanged.
+func (b *binder) setScalar(v reflect.Value, key, value string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if value == "" && v.Kind() != reflect.String {
+		return nil
+	}
+
+	var err error
+	switch {
+	case reflect.PointerTo(v.Type()).Implements(textUnmarshalerType):
+		err = v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(value)
+		v.SetInt(int64(d))
+	default:
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(value)
+		case reflect.Bool:
+			var x bool
+			x, err = strconv.ParseBool(value)
+			v.SetBool(x)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var x int64
+			x, err = strconv.ParseInt(value, 10, v.Type().Bits())
+			v.SetInt(x)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			var x uint64
+			x, err = strconv.ParseUint(value, 10, v.Type().Bits())
+			v.SetUint(x)
+		case reflect.Float32, reflect.Float64:
+			var x float64
+			x, err = strconv.ParseFloat(value, v.Type().Bits())
+			v.SetFloat(x)
+		}
+	}
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid value for %q", key)).WithCause(err)
+	}
+	return nil
+}
+
+// isScalar reports whether values of t are parsed from a single string.
+func isScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
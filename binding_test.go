@@ -0,0 +1,243 @@
+package draupnir
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// bindStatus serves req with a route on pattern whose handler runs bind, and returns
+// the response status.
+func bindStatus(t *testing.T, req *http.Request, pattern string, bind func(c *Context) error) int {
+	t.Helper()
+	r := New().WithRequestLogging(false)
+	r.HandleFunc(req.Method, pattern, func(c *Context) error {
+		if err := bind(c); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+// formPart is a field, or a file when filename is set, of a multipart test body.
+type formPart struct {
+	name, filename, content string
+}
+
+func multipartRequest(t *testing.T, target string, parts ...formPart) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var w io.Writer
+		var err error
+		if p.filename != "" {
+			w, err = mw.CreateFormFile(p.name, p.filename)
+		} else {
+			w, err = mw.CreateFormField(p.name)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, p.content)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set(HeaderContentType, mw.FormDataContentType())
+	return req
+}
+
+func bodyRequest(method, target, contentType, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(HeaderContentType, contentType)
+	}
+	return req
+}
+
+func TestBindContentType(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name" form:"name"`
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"json", bodyRequest(http.MethodPost, "/", MIMEApplicationJSON, `{"name":"bob"}`), http.StatusOK},
+		{"json charset", bodyRequest(http.MethodPost, "/", "application/json; charset=utf-8", `{"name":"bob"}`), http.StatusOK},
+		{"json suffix", bodyRequest(http.MethodPost, "/", "application/merge-patch+json", `{"name":"bob"}`), http.StatusOK},
+		{"xml", bodyRequest(http.MethodPost, "/", MIMEApplicationXML, `<user><name>bob</name></user>`), http.StatusOK},
+		{"text xml", bodyRequest(http.MethodPost, "/", MIMETextXML, `<user><name>bob</name></user>`), http.StatusOK},
+		{"form", bodyRequest(http.MethodPost, "/", MIMEApplicationForm, "name=bob"), http.StatusOK},
+		{"multipart", multipartRequest(t, "/", formPart{"name", "", "bob"}), http.StatusOK},
+		{"query", bodyRequest(http.MethodGet, "/?name=bob", "", ""), http.StatusOK},
+		{"malformed json", bodyRequest(http.MethodPost, "/", MIMEApplicationJSON, `{"name":`), http.StatusBadRequest},
+		{"malformed xml", bodyRequest(http.MethodPost, "/", MIMEApplicationXML, `<user>`), http.StatusBadRequest},
+		{"unsupported", bodyRequest(http.MethodPost, "/", MIMETextPlain, "bob"), http.StatusUnsupportedMediaType},
+		{"body without type", bodyRequest(http.MethodPost, "/", "", "bob"), http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		var u user
+		code := bindStatus(t, tt.req, "/", func(c *Context) error { return c.Bind(&u) })
+		if code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.name, code, tt.code)
+		}
+		if code == http.StatusOK && u.Name != "bob" {
+			t.Errorf("%s: name = %q, want bob", tt.name, u.Name)
+		}
+	}
+
+	// The specific binders insist on their content type
+	specific := []struct {
+		name string
+		bind func(c *Context, obj any) error
+		req  *http.Request
+	}{
+		{"BindXML", (*Context).BindXML, bodyRequest(http.MethodPost, "/", MIMEApplicationJSON, `{}`)},
+		{"BindForm", (*Context).BindForm, bodyRequest(http.MethodPost, "/", MIMEApplicationJSON, `{}`)},
+	}
+	for _, tt := range specific {
+		var u user
+		if code := bindStatus(t, tt.req, "/", func(c *Context) error { return tt.bind(c, &u) }); code != http.StatusUnsupportedMediaType {
+			t.Errorf("%s with JSON: status = %d, want 415", tt.name, code)
+		}
+	}
+}
+
+func TestBindFormNested(t *testing.T) {
+	type item struct {
+		Name string `form:"name"`
+		Qty  *int   `form:"qty"`
+	}
+	type order struct {
+		Items []item `form:"items"`
+		User  struct {
+			Email string `form:"email"`
+		} `form:"user"`
+		Tags []string `form:"tags"`
+		IDs  []int    `form:"ids"`
+		Note *string  `form:"note"`
+	}
+
+	var o order
+	body := "items[0].name=a&items[0].qty=2&items[2].name=c&user.email=x%40y.z&tags=a&tags=b&ids[]=1&ids[]=2"
+	if code := bindStatus(t, bodyRequest(http.MethodPost, "/", MIMEApplicationForm, body), "/", func(c *Context) error { return c.BindForm(&o) }); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if len(o.Items) != 3 || o.Items[0].Name != "a" || o.Items[0].Qty == nil || *o.Items[0].Qty != 2 || o.Items[1].Name != "" || o.Items[2].Name != "c" {
+		t.Errorf("items = %+v, want a, empty and c", o.Items)
+	}
+	if o.User.Email != "x@y.z" || !slices.Equal(o.Tags, []string{"a", "b"}) || !slices.Equal(o.IDs, []int{1, 2}) || o.Note != nil {
+		t.Errorf("order = %+v", o)
+	}
+
+	for _, body := range []string{"items[1000].name=a", "items[-1].name=a", "items[x].name=a", "ids=x", "items[0].qty=two"} {
+		var o order
+		if code := bindStatus(t, bodyRequest(http.MethodPost, "/", MIMEApplicationForm, body), "/", func(c *Context) error { return c.BindForm(&o) }); code != http.StatusBadRequest {
+			t.Errorf("BindForm(%s): status = %d, want 400", body, code)
+		}
+	}
+	var ok order
+	if code := bindStatus(t, bodyRequest(http.MethodPost, "/", MIMEApplicationForm, "items[999].name=a"), "/", func(c *Context) error { return c.BindForm(&ok) }); code != http.StatusOK || len(ok.Items) != 1000 {
+		t.Errorf("BindForm(items[999]): status = %d, %d items, want 200 and 1000 items", code, len(ok.Items))
+	}
+}
+
+type Audit struct {
+	CreatedBy string `form:"created_by"`
+}
+
+type paging struct {
+	Page int `query:"page"`
+}
+
+func TestBindEmbedded(t *testing.T) {
+	var v struct {
+		Audit
+		paging
+		Name string `form:"name"`
+	}
+	req := bodyRequest(http.MethodGet, "/?created_by=bob&page=3&name=x", "", "")
+	if code := bindStatus(t, req, "/", func(c *Context) error { return c.BindQuery(&v) }); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if v.CreatedBy != "bob" || v.Page != 3 || v.Name != "x" {
+		t.Errorf("bound %+v, want promoted fields set", v)
+	}
+}
+
+func TestBindFormFiles(t *testing.T) {
+	var v struct {
+		Name   string                  `form:"name"`
+		Avatar *multipart.FileHeader   `form:"avatar"`
+		Docs   []*multipart.FileHeader `form:"docs"`
+		Cover  multipart.FileHeader    `form:"cover"`
+		Absent *multipart.FileHeader   `form:"absent"`
+	}
+	req := multipartRequest(t, "/",
+		formPart{"name", "", "bob"},
+		formPart{"avatar", "me.png", "png"},
+		formPart{"docs", "a.txt", "a"},
+		formPart{"docs", "b.txt", "b"},
+		formPart{"cover", "cover.jpg", "jpg"},
+	)
+	if code := bindStatus(t, req, "/", func(c *Context) error { return c.Bind(&v) }); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if v.Name != "bob" || v.Avatar == nil || v.Avatar.Filename != "me.png" || v.Cover.Filename != "cover.jpg" || v.Absent != nil {
+		t.Errorf("bound %+v", v)
+	}
+	if len(v.Docs) != 2 || v.Docs[0].Filename != "a.txt" || v.Docs[1].Filename != "b.txt" {
+		t.Errorf("docs = %v, want a.txt and b.txt", v.Docs)
+	}
+}
+
+func TestBindHeader(t *testing.T) {
+	var v struct {
+		RequestID string   `header:"x-request-id"`
+		Retries   int      `header:"X-RETRIES"`
+		Accept    []string `header:"accept"`
+		Missing   *string  `header:"x-missing"`
+	}
+	req := bodyRequest(http.MethodGet, "/", "", "")
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Set("X-Retries", "3")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+	if code := bindStatus(t, req, "/", func(c *Context) error { return c.BindHeader(&v) }); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if v.RequestID != "abc" || v.Retries != 3 || !slices.Equal(v.Accept, []string{"text/html", "application/json"}) || v.Missing != nil {
+		t.Errorf("bound %+v", v)
+	}
+}
+
+func TestBindURI(t *testing.T) {
+	var v struct {
+		ID   int    `uri:"id"`
+		Slug string `uri:"slug"`
+	}
+	req := bodyRequest(http.MethodGet, "/posts/42/hello", "", "")
+	if code := bindStatus(t, req, "/posts/:id/:slug", func(c *Context) error { return c.BindURI(&v) }); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if v.ID != 42 || v.Slug != "hello" {
+		t.Errorf("bound %+v, want 42 hello", v)
+	}
+
+	req = bodyRequest(http.MethodGet, "/posts/x/hello", "", "")
+	if code := bindStatus(t, req, "/posts/:id/:slug", func(c *Context) error { return c.BindURI(&v) }); code != http.StatusBadRequest {
+		t.Errorf("non-numeric id: status = %d, want 400", code)
+	}
+}
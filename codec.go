@@ -0,0 +1,176 @@
+package draupnir
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/kashari/draupnir/ws"
+)
+
+// Encoder writes JSON values to a stream.
+type Encoder = ws.Encoder
+
+// Decoder reads JSON values from a stream.
+type Decoder = ws.Decoder
+
+// Codec encodes and decodes the JSON of a Router. It is used by Context.JSON, the JSON
+// binders and the JSON helpers of connections opened with Context.SwitchToWebSocket.
+// Set it with WithCodec to use a faster encoder than encoding/json.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// JSONCodec is the Codec backed by encoding/json. The default codec of a Router is a
+// JSONCodec with EscapeHTML set, which encodes like json.Marshal.
+type JSONCodec struct {
+	DisallowUnknownFields bool   // reject objects with keys matching no field of the target struct
+	UseNumber             bool   // decode numbers into interface values as json.Number instead of float64
+	EscapeHTML            bool   // escape <, > and & in strings, for embedding in HTML
+	Indent                string // pretty print with this indent, e.g. "  "; compact when empty
+}
+
+var _ ws.JSONCodec = Codec(nil)
+
+// WithCodec sets the codec used to encode and decode JSON; nil restores the default.
+func (r *Router) WithCodec(codec Codec) *Router {
+	if codec == nil {
+		codec = &JSONCodec{EscapeHTML: true}
+	}
+	r.codec = codec
+	return r
+}
+
+// Marshal returns the JSON encoding of v, without a trailing newline.
+func (j *JSONCodec) Marshal(v any) ([]byte, error) {
+	// Fast path for unnamed scalar types, which cannot implement json.Marshaler.
+	switch v := v.(type) {
+	case nil:
+		return []byte("null"), nil
+	case string:
+		return appendJSONString(make([]byte, 0, len(v)+2), v, j.EscapeHTML), nil
+	case bool:
+		return strconv.AppendBool(nil, v), nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case int32:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case uint:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, v, 10), nil
+	case uint32:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	}
+
+	if j.EscapeHTML && j.Indent == "" {
+		return json.Marshal(v)
+	}
+	var buf bytes.Buffer
+	if err := j.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Unmarshal parses the JSON document in data into v.
+func (j *JSONCodec) Unmarshal(data []byte, v any) error {
+	if !j.DisallowUnknownFields && !j.UseNumber {
+		return json.Unmarshal(data, v)
+	}
+	dec := j.decoder(bytes.NewReader(data))
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// Like json.Unmarshal, reject anything but whitespace after the document.
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("json: invalid data after top-level value")
+	}
+	return nil
+}
+
+// NewEncoder returns a json.Encoder configured with the codec's options.
+func (j *JSONCodec) NewEncoder(w io.Writer) Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(j.EscapeHTML)
+	if j.Indent != "" {
+		enc.SetIndent("", j.Indent)
+	}
+	return enc
+}
+
+// NewDecoder returns a json.Decoder configured with the codec's options.
+func (j *JSONCodec) NewDecoder(r io.Reader) Decoder {
+	return j.decoder(r)
+}
+
+func (j *JSONCodec) decoder(r io.Reader) *json.Decoder {
+	dec := json.NewDecoder(r)
+	if j.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if j.UseNumber {
+		dec.UseNumber()
+	}
+	return dec
+}
+
+// appendJSONString appends s to dst as a JSON string, escaped like encoding/json.
+func appendJSONString(dst []byte, s string, escapeHTML bool) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= ' ' && b != '"' && b != '\\' && (!escapeHTML || b != '<' && b != '>' && b != '&') {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+		case r == '\u2028' || r == '\u2029':
+			// Valid JSON, but not valid JavaScript inside a script element.
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,109 @@
+package draupnir
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONStrings(t *testing.T) {
+	tests := []string{
+		"",
+		"plain",
+		`a"b`,
+		`back\slash`,
+		"tab\tnewline\nreturn\rbell\a\x00\x1f\b\f",
+		"<script>&amp;</script>",
+		"invalid \xff utf-8 \xe2\x28\xa1",
+		"line\u2028paragraph\u2029separators",
+		"h\u00e9llo \u4e16\u754c \U0001f389",
+	}
+	for _, s := range tests {
+		r := New().WithRequestLogging(false)
+		r.GET("/", func(c *Context) error { return c.JSON(http.StatusOK, s) })
+		got := serve(r, http.MethodGet, "/").Body.Bytes()
+
+		want, _ := json.Marshal(s)
+		// Newer versions of encoding/json write invalid bytes as a literal U+FFFD
+		want = bytes.ReplaceAll(want, []byte("\ufffd"), []byte(`\ufffd`))
+		if !json.Valid(got) || !bytes.Equal(got, want) {
+			t.Errorf("JSON(%q) = %s, want %s", s, got, want)
+		}
+	}
+
+	got, _ := (&JSONCodec{}).Marshal("<&>")
+	if string(got) != `"<&>"` {
+		t.Errorf("Marshal without EscapeHTML = %s, want \"<&>\"", got)
+	}
+}
+
+func TestJSONCodecOptions(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	var u user
+	if err := (&JSONCodec{}).Unmarshal([]byte(`{"name":"a","age":3}`), &u); err != nil || u.Name != "a" {
+		t.Errorf("Unmarshal with unknown field = %+v, %v, want it ignored", u, err)
+	}
+	if err := (&JSONCodec{DisallowUnknownFields: true}).Unmarshal([]byte(`{"name":"a","age":3}`), &u); err == nil {
+		t.Error("DisallowUnknownFields: Unmarshal with unknown field succeeded")
+	}
+
+	var v map[string]any
+	if err := (&JSONCodec{UseNumber: true}).Unmarshal([]byte(`{"n":12345678901234567890}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := v["n"].(json.Number); !ok || n.String() != "12345678901234567890" {
+		t.Errorf("UseNumber: n = %#v, want json.Number", v["n"])
+	}
+
+	for _, codec := range []*JSONCodec{{}, {UseNumber: true}, {DisallowUnknownFields: true}} {
+		for _, data := range []string{`{"name":"a"} {}`, `{"name":"a"}x`, `"a" "b"`} {
+			if err := codec.Unmarshal([]byte(data), &u); err == nil {
+				t.Errorf("%+v: Unmarshal(%s) succeeded, want trailing data rejected", codec, data)
+			}
+		}
+		if err := codec.Unmarshal([]byte(" {\"name\":\"a\"}\n\t"), &u); err != nil {
+			t.Errorf("%+v: Unmarshal with trailing whitespace: %v", codec, err)
+		}
+	}
+
+	data, err := (&JSONCodec{Indent: "  "}).Marshal(map[string]int{"a": 1})
+	if err != nil || string(data) != "{\n  \"a\": 1\n}" {
+		t.Errorf("Indent: Marshal = %q, %v", data, err)
+	}
+}
+
+func TestWithCodec(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	r := New().WithRequestLogging(false).WithCodec(&JSONCodec{DisallowUnknownFields: true, Indent: "\t"})
+	r.POST("/", func(c *Context) error {
+		var it item
+		if err := c.Bind(&it); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, it)
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(`{"name":"a"}`); w.Code != http.StatusOK || w.Body.String() != "{\n\t\"name\": \"a\"\n}" {
+		t.Errorf("POST known fields = %d %q, want 200 and the body indented by the codec", w.Code, w.Body.String())
+	}
+	if w := post(`{"name":"a","x":1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("POST unknown field = %d, want 400", w.Code)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -19,11 +20,51 @@ import (
 	"github.com/kashari/golog"
 )
 
+// Param gets a path parameter by key
 func (c *Context) Param(key string) string {
-	if params, ok := c.Request.Context().Value(ctxKey("params")).(map[string]string); ok {
-		return params[key]
+	p, _ := c.param(key)
+	return p.Value
+}
+
+// param looks up a path parameter captured by the router
+func (c *Context) param(key string) (Param, bool) {
+	for _, p := range c.params {
+		if p.Key == key {
+			return p, true
+		}
 	}
-	return ""
+	return Param{}, false
+}
+
+// Params returns the path parameters captured by the router, host parameters first.
+// The slice is reused for the next request once the handler returns.
+func (c *Context) Params() []Param {
+	return c.params
+}
+
+// ParamValue gets a path parameter converted according to its route constraint:
+// int64 for <int>, uint64 for <uint>, float64 for <float> and string otherwise.
+// It returns nil if the parameter does not exist.
+func (c *Context) ParamValue(key string) any {
+	p, ok := c.param(key)
+	if !ok {
+		return nil
+	}
+
+	// The router only matched the route if the value satisfied the constraint,
+	// so the conversions below cannot fail.
+	switch p.Constraint {
+	case "int":
+		v, _ := strconv.ParseInt(p.Value, 10, 64)
+		return v
+	case "uint":
+		v, _ := strconv.ParseUint(p.Value, 10, 64)
+		return v
+	case "float":
+		v, _ := strconv.ParseFloat(p.Value, 64)
+		return v
+	}
+	return p.Value
 }
 
 // ParamInt gets a path parameter as int by key
@@ -147,13 +188,18 @@ func (c *Context) parseForm() error {
 		return nil
 	}
 
-	contentType := c.Request.Header.Get(HeaderContentType)
-	if strings.HasPrefix(contentType, MIMEApplicationForm) {
+	switch c.mediaType() {
+	case MIMEApplicationForm:
 		if err := c.Request.ParseForm(); err != nil {
 			return err
 		}
 		c.formValues = c.Request.Form
-	} else {
+	case MIMEMultipartForm:
+		if err := c.parseMultipartForm(); err != nil {
+			return err
+		}
+		c.formValues = c.Request.Form
+	default:
 		c.formValues = make(url.Values)
 	}
 
@@ -161,27 +207,27 @@ func (c *Context) parseForm() error {
 	return nil
 }
 
-// FormFile gets a file from multipart form data
+// FormFile gets a file from multipart form data, or http.ErrMissingFile when there is none
 func (c *Context) FormFile(key string) (*multipart.FileHeader, error) {
 	if !c.multipartParsed {
 		if err := c.parseMultipartForm(); err != nil {
 			return nil, err
 		}
 	}
-	if c.multipartForm == nil {
+	if c.multipartForm == nil || len(c.multipartForm.File[key]) == 0 {
 		return nil, http.ErrMissingFile
 	}
 	return c.multipartForm.File[key][0], nil
 }
 
-// FormFiles gets all files for a key from multipart form data
+// FormFiles gets all files for a key from multipart form data, or http.ErrMissingFile when there are none
 func (c *Context) FormFiles(key string) ([]*multipart.FileHeader, error) {
 	if !c.multipartParsed {
 		if err := c.parseMultipartForm(); err != nil {
 			return nil, err
 		}
 	}
-	if c.multipartForm == nil {
+	if c.multipartForm == nil || len(c.multipartForm.File[key]) == 0 {
 		return nil, http.ErrMissingFile
 	}
 	return c.multipartForm.File[key], nil
@@ -195,8 +241,7 @@ func (c *Context) parseMultipartForm() error {
 
 	contentType := c.Request.Header.Get(HeaderContentType)
 	if strings.HasPrefix(contentType, MIMEMultipartForm) {
-		// 32 MB max memory
-		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		if err := c.Request.ParseMultipartForm(c.router.multipartMemory); err != nil {
 			return err
 		}
 		c.multipartForm = c.Request.MultipartForm
@@ -206,105 +251,86 @@ func (c *Context) parseMultipartForm() error {
 	return nil
 }
 
-// Set sets a value in the context store
+// Set sets a value in the context store.
+// The store is safe for concurrent use, and its values are also visible through
+// Request.Context().Value(key). See Key for collision-free typed keys.
 func (c *Context) Set(key string, value interface{}) {
-	c.store[key] = value
+	c.values().set(key, value)
 }
 
 // Get gets a value from the context store
 func (c *Context) Get(key string) (interface{}, bool) {
-	val, ok := c.store[key]
-	return val, ok
+	return c.lookup(key)
 }
 
 // GetString gets a string value from the context store
 func (c *Context) GetString(key string) string {
-	if val, ok := c.store[key]; ok {
-		if s, ok := val.(string); ok {
-			return s
-		}
-	}
-	return ""
+	s, _ := GetAs[string](c, key)
+	return s
 }
 
 // GetInt gets an int value from the context store
 func (c *Context) GetInt(key string) int {
-	if val, ok := c.store[key]; ok {
-		if i, ok := val.(int); ok {
-			return i
-		}
-	}
-	return 0
+	i, _ := GetAs[int](c, key)
+	return i
 }
 
 // GetInt64 gets an int64 value from the context store
 func (c *Context) GetInt64(key string) int64 {
-	if val, ok := c.store[key]; ok {
-		if i, ok := val.(int64); ok {
-			return i
-		}
-	}
-	return 0
+	i, _ := GetAs[int64](c, key)
+	return i
 }
 
 // GetFloat64 gets a float64 value from the context store
 func (c *Context) GetFloat64(key string) float64 {
-	if val, ok := c.store[key]; ok {
-		if f, ok := val.(float64); ok {
-			return f
-		}
-	}
-	return 0
+	f, _ := GetAs[float64](c, key)
+	return f
 }
 
 // GetBool gets a bool value from the context store
 func (c *Context) GetBool(key string) bool {
-	if val, ok := c.store[key]; ok {
-		if b, ok := val.(bool); ok {
-			return b
-		}
-	}
-	return false
+	b, _ := GetAs[bool](c, key)
+	return b
 }
 
 // BindJSON binds JSON body to a struct
 func (c *Context) BindJSON(obj any) error {
-	return c.decodeJSON(obj)
+	if !isJSON(c.mediaType()) {
+		return unsupportedMediaType(MIMEApplicationJSON)
+	}
+	return c.validateBound(obj, c.decodeJSON(obj))
 }
 
 // decodeJSON decodes JSON body to a struct
 func (c *Context) decodeJSON(obj interface{}) error {
-	// Get the content type
-	contentType := c.Request.Header.Get(HeaderContentType)
-	if !strings.HasPrefix(contentType, MIMEApplicationJSON) {
-		return errors.New("content-type is not application/json")
-	}
-
 	// Read the body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		return err
+		return bodyError("failed to read request body", err)
 	}
 	defer c.Request.Body.Close()
 
 	// Reset the body
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
-	// Use a faster JSON decoder
-	return jsonUnmarshal(body, obj)
+	if err := c.router.codec.Unmarshal(body, obj); err != nil {
+		return bodyError("invalid JSON body", err)
+	}
+	return nil
 }
 
 // JSON sends a JSON response
 func (c *Context) JSON(code int, obj interface{}) error {
-	c.Writer.Header().Set(HeaderContentType, MIMEApplicationJSON)
-	c.Writer.WriteHeader(code)
-	c.statusCode = code
-
-	data, err := jsonMarshal(obj)
+	// Encode first, so that an encoding error can still be rendered by the error handler.
+	data, err := c.router.codec.Marshal(obj)
 	if err != nil {
 		return err
 	}
 
+	c.Writer.Header().Set(HeaderContentType, MIMEApplicationJSON)
+	c.Writer.WriteHeader(code)
+	c.statusCode = code
+
 	_, err = c.Writer.Write(data)
 	return err
 }
@@ -440,6 +466,74 @@ func (c *Context) ClientIP() string {
 	return ip
 }
 
+var _ context.Context = (*Context)(nil)
+
+// Deadline implements context.Context using the request context.
+func (c *Context) Deadline() (time.Time, bool) {
+	return c.Request.Context().Deadline()
+}
+
+// Done implements context.Context using the request context, so that work
+// bound to c stops when the client goes away.
+func (c *Context) Done() <-chan struct{} {
+	return c.Request.Context().Done()
+}
+
+// Err implements context.Context using the request context.
+func (c *Context) Err() error {
+	return c.Request.Context().Err()
+}
+
+// Value implements context.Context. It returns the value stored under key with Set
+// or a Key, falling back to the request context.
+func (c *Context) Value(key any) any {
+	if val, ok := c.lookup(key); ok {
+		return val
+	}
+	return c.Request.Context().Value(key)
+}
+
+// Copy returns a copy of c that stays valid after the handler returns, for use in
+// goroutines and WorkerPool tasks. It shares the request, parameters and store of c,
+// but cannot write the response: writes through its Writer fail.
+// Its context is still canceled with the request; use context.WithoutCancel to detach it.
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Request:         c.Request,
+		path:            c.path,
+		method:          c.method,
+		pattern:         c.pattern,
+		router:          c.router,
+		index:           abortIndex,
+		params:          slices.Clone(c.params),
+		bodyParsed:      c.bodyParsed,
+		queryParsed:     c.queryParsed,
+		formParsed:      c.formParsed,
+		multipartParsed: c.multipartParsed,
+		query:           c.query,
+		formValues:      c.formValues,
+		multipartForm:   c.multipartForm,
+		statusCode:      c.statusCode,
+	}
+	cp.writer = responseWriter{ResponseWriter: detachedWriter{}, status: http.StatusOK}
+	cp.Writer = &cp.writer
+
+	c.storeMu.Lock()
+	cp.store = c.store
+	c.storeMu.Unlock()
+	return cp
+}
+
+// errDetached is returned by writes through the Writer of a copied Context.
+var errDetached = errors.New("draupnir: cannot write the response from a copied Context")
+
+// detachedWriter is the ResponseWriter of a copied Context.
+type detachedWriter struct{}
+
+func (detachedWriter) Header() http.Header        { return http.Header{} }
+func (detachedWriter) Write([]byte) (int, error)  { return 0, errDetached }
+func (detachedWriter) WriteHeader(statusCode int) {}
+
 // WithContext sets the request context
 func (c *Context) WithContext(ctx context.Context) *Context {
 	c.Request = c.Request.WithContext(ctx)
@@ -456,6 +550,11 @@ func (c *Context) URL() *url.URL {
 	return c.Request.URL
 }
 
+// URLFor builds the path of a named route, see Router.URL
+func (c *Context) URLFor(name string, pairs ...string) (string, error) {
+	return c.router.URL(name, pairs...)
+}
+
 // Path returns the current path
 func (c *Context) Path() string {
 	return c.path
@@ -486,6 +585,6 @@ func (c *Context) SwitchToWebSocket() (*ws.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	// c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxKey("ws"), conn))
+	conn.SetJSONCodec(c.router.codec)
 	return conn, nil
 }
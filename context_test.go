@@ -0,0 +1,81 @@
+package draupnir
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type requestKey struct{}
+
+func TestContextImplementsContext(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.WithValue(context.Background(), requestKey{}, "request"), time.Now().Add(time.Hour))
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	c := &Context{}
+	c.reset(New(), httptest.NewRecorder(), req)
+
+	var _ context.Context = c
+	if d, ok := c.Deadline(); !ok || !d.Equal(mustDeadline(ctx)) {
+		t.Errorf("Deadline() = %v, %v, want the request deadline", d, ok)
+	}
+	if c.Err() != nil {
+		t.Errorf("Err() = %v before cancellation", c.Err())
+	}
+
+	if v := c.Value(requestKey{}); v != "request" {
+		t.Errorf("Value(requestKey) = %v, want the request context value", v)
+	}
+	// The store is checked before the request context
+	c.Set("shadowed", "store")
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), "shadowed", "request"))
+	if v := c.Value("shadowed"); v != "store" {
+		t.Errorf("Value(shadowed) = %v, want the store value", v)
+	}
+
+	cancel()
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done() not closed after the request context was canceled")
+	}
+	if !errors.Is(c.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want context.Canceled", c.Err())
+	}
+}
+
+func mustDeadline(ctx context.Context) time.Time {
+	d, _ := ctx.Deadline()
+	return d
+}
+
+func TestContextCopy(t *testing.T) {
+	copies := make(chan *Context, 1)
+	r := New().WithRequestLogging(false)
+	r.GET("/users/:id", func(c *Context) error {
+		c.Set("user", "gopher")
+		copies <- c.Copy()
+		return c.String(http.StatusOK, "ok")
+	})
+	serve(r, http.MethodGet, "/users/42?tab=posts")
+
+	cp := <-copies
+	// Serve another request, so that the pooled Context of the first is reused
+	serve(r, http.MethodGet, "/users/7?tab=likes")
+
+	if cp.Param("id") != "42" || cp.Query("tab") != "posts" || cp.pattern != "/users/:id" {
+		t.Errorf("copy has id %q, tab %q, pattern %q after its Context was recycled", cp.Param("id"), cp.Query("tab"), cp.pattern)
+	}
+	if cp.GetString("user") != "gopher" || cp.Value("user") != "gopher" {
+		t.Errorf("copy lost the store: %q", cp.GetString("user"))
+	}
+
+	if _, err := cp.Writer.Write([]byte("late")); !errors.Is(err, errDetached) {
+		t.Errorf("Writer.Write on a copy = %v, want errDetached", err)
+	}
+	if err := cp.String(http.StatusOK, "late"); !errors.Is(err, errDetached) {
+		t.Errorf("String on a copy = %v, want errDetached", err)
+	}
+}
@@ -0,0 +1,123 @@
+package draupnir
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kashari/golog"
+)
+
+// HTTPError is an error carrying the HTTP status code to respond with.
+// Message is sent to the client, while Err, the internal cause, is only logged.
+type HTTPError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+// NewHTTPError returns an HTTPError with a status code and a public message,
+// which defaults to the status text of the code.
+func NewHTTPError(code int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(code)
+	}
+	return &HTTPError{Code: code, Message: message}
+}
+
+// WithCause sets the internal cause of the error.
+func (e *HTTPError) WithCause(err error) *HTTPError {
+	e.Err = err
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorHandlerFunc renders an error returned by a handler or middleware.
+type ErrorHandlerFunc func(*Context, error)
+
+// ErrorHandler sets the handler rendering errors returned by handlers and middleware,
+// replacing DefaultErrorHandler. The router logs each error once, with the request and
+// route it came from, before calling the handler. Errors returned after the response
+// has started are only logged.
+func (r *Router) ErrorHandler(handler ErrorHandlerFunc) *Router {
+	r.errorHandler = handler
+	return r
+}
+
+// DefaultErrorHandler responds with the status code and message of an *HTTPError in
+// the error chain, or with 500 Internal Server Error, as plain text. ValidationErrors
+// are rendered as 422 Unprocessable Entity with a JSON body keyed by field:
+//
+//	{"error": "validation failed", "fields": {"name": "is required"}}
+func DefaultErrorHandler(c *Context, err error) {
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		c.JSON(http.StatusUnprocessableEntity, map[string]any{
+			"error":  "validation failed",
+			"fields": ve.Fields(),
+		})
+		return
+	}
+	code, message := errorStatus(err)
+	http.Error(c.Writer, message, code)
+}
+
+// errorStatus returns the status code and public message for err.
+func errorStatus(err error) (int, string) {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.Code, he.Message
+	}
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		return http.StatusUnprocessableEntity, "validation failed"
+	}
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge)
+	}
+	return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+}
+
+// bodyError returns a 400 error with message for a request body that cannot be read
+// or decoded, or a 413 error when the body exceeds its size limit.
+func bodyError(message string, err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, "").WithCause(err)
+	}
+	return NewHTTPError(http.StatusBadRequest, message).WithCause(err)
+}
+
+// handleError logs an error returned by the pipeline and renders it with the error handler.
+// Server errors are logged as errors and client errors as warnings.
+func (r *Router) handleError(c *Context, err error) {
+	route := c.pattern
+	if route == "" {
+		route = "-"
+	}
+	if code, _ := errorStatus(err); code >= http.StatusInternalServerError {
+		golog.Error("Request {} {} (route {}) failed: {}", c.method, c.path, route, err.Error())
+	} else {
+		golog.Warn("Request {} {} (route {}) failed: {}", c.method, c.path, route, err.Error())
+	}
+
+	if c.writer.written() {
+		return
+	}
+	handler := r.errorHandler
+	if handler == nil {
+		handler = DefaultErrorHandler
+	}
+	handler(c, err)
+}
@@ -0,0 +1,83 @@
+package draupnir
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := NewHTTPError(http.StatusServiceUnavailable, "").WithCause(cause)
+	if err.Message != "Service Unavailable" {
+		t.Errorf("Message = %q, want the status text", err.Message)
+	}
+	if !errors.Is(err, cause) || err.Error() != "503 Service Unavailable: connection refused" {
+		t.Errorf("Error() = %q, Is(cause) = %v", err.Error(), errors.Is(err, cause))
+	}
+
+	r := New().WithRequestLogging(false)
+	r.GET("/http", func(c *Context) error {
+		return fmt.Errorf("loading: %w", NewHTTPError(http.StatusForbidden, "no access").WithCause(errors.New("secret detail")))
+	})
+	r.GET("/plain", func(c *Context) error { return errors.New("secret detail") })
+
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{"/http", http.StatusForbidden, "no access\n"},
+		{"/plain", http.StatusInternalServerError, "Internal Server Error\n"},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, tt.target)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.target, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("GET %s exposed the cause: %q", tt.target, w.Body.String())
+		}
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	var handled []error
+	r := New().WithRequestLogging(false)
+	r.ErrorHandler(func(c *Context, err error) {
+		handled = append(handled, err)
+		code, message := errorStatus(err)
+		c.JSON(code, map[string]string{"message": message})
+	})
+	r.Use(func(c *Context) error {
+		if c.Query("deny") != "" {
+			return NewHTTPError(http.StatusUnauthorized, "")
+		}
+		return c.Next()
+	})
+	r.GET("/fail", func(c *Context) error { return errors.New("boom") })
+	r.GET("/started", func(c *Context) error {
+		c.String(http.StatusAccepted, "partial")
+		return errors.New("failed after writing")
+	})
+
+	w := serve(r, http.MethodGet, "/fail")
+	if w.Code != http.StatusInternalServerError || w.Body.String() != `{"message":"Internal Server Error"}` {
+		t.Errorf("GET /fail = %d %q", w.Code, w.Body.String())
+	}
+	w = serve(r, http.MethodGet, "/fail?deny=1")
+	if w.Code != http.StatusUnauthorized || w.Body.String() != `{"message":"Unauthorized"}` {
+		t.Errorf("GET /fail?deny=1 = %d %q", w.Code, w.Body.String())
+	}
+	if len(handled) != 2 {
+		t.Fatalf("error handler called %d times, want 2", len(handled))
+	}
+
+	// Once the response has started, the error is only logged
+	w = serve(r, http.MethodGet, "/started")
+	if w.Code != http.StatusAccepted || w.Body.String() != "partial" || len(handled) != 2 {
+		t.Errorf("GET /started = %d %q, error handler called %d times, want 202 \"partial\" and 2 calls", w.Code, w.Body.String(), len(handled))
+	}
+}
@@ -13,36 +13,34 @@ func main() {
 		WithWorkerPool(10).
 		WithRateLimiter(10, 1*time.Second)
 
-	router.GET("/", func(ctx *draupnir.Context) {
-		ctx.String(200, "Hello, World!")
+	router.GET("/", func(ctx *draupnir.Context) error {
+		return ctx.String(200, "Hello, World!")
 	})
 
-	router.GET("/hello/:name", func(ctx *draupnir.Context) {
+	router.GET("/hello/:name", func(ctx *draupnir.Context) error {
 		name := ctx.Param("name")
-		ctx.String(200, "Hello, %s!", name)
+		return ctx.String(200, "Hello, %s!", name)
 	})
 
-	router.POST("/submit", func(ctx *draupnir.Context) {
+	router.POST("/submit", func(ctx *draupnir.Context) error {
 		var data struct {
 			Name string `json:"name"`
 			Age  int    `json:"age"`
 		}
 
 		if err := ctx.BindJSON(&data); err != nil {
-			ctx.String(400, "Invalid JSON: %s", err)
-			return
+			return draupnir.NewHTTPError(400, "Invalid JSON").WithCause(err)
 		}
-		ctx.String(200, "Received: Name=%s, Age=%d", data.Name, data.Age)
+		return ctx.String(200, "Received: Name=%s, Age=%d", data.Name, data.Age)
 	})
 
-	router.GET("/stream", func(ctx *draupnir.Context) {
+	router.GET("/stream", func(ctx *draupnir.Context) error {
 		filepath := ctx.Query("file")
 		if filepath == "" {
-			ctx.String(400, "File path is required")
-			return
+			return draupnir.NewHTTPError(400, "File path is required")
 		}
 
-		ctx.Streamer(filepath)
+		return ctx.Streamer(filepath)
 	})
 
 	router.WEBSOCKET("/v1/ws/chat", func(ws *draupnir.WebSocketConn) {
@@ -56,11 +54,10 @@ func main() {
 		}
 	})
 
-	router.GET("/ws/chat", func(ctx *draupnir.Context) {
+	router.GET("/ws/chat", func(ctx *draupnir.Context) error {
 		conn, err := ctx.SwitchToWebSocket()
 		if err != nil {
-			ctx.String(500, "Failed to upgrade to WebSocket: %s", err)
-			return
+			return draupnir.NewHTTPError(500, "Failed to upgrade to WebSocket").WithCause(err)
 		}
 		defer conn.Close()
 		conn.WriteMessage(ws.TextMessage, []byte("Welcome to the Draupnir WebSocket chat!"))
@@ -79,10 +76,11 @@ func main() {
 			}
 		}
 		golog.Info("WebSocket connection closed")
+		return nil
 	})
 
-	router.GET("/chat", func(ctx *draupnir.Context) {
-		ctx.HTML(200, `
+	router.GET("/chat", func(ctx *draupnir.Context) error {
+		return ctx.HTML(200, `
 			<!DOCTYPE html>
 			<html lang="en">
 			<head>
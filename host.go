@@ -0,0 +1,203 @@
+package draupnir
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Host creates a route group that only matches requests whose Host header matches pattern.
+// Labels starting with ':' match any single label and are available through Context.Param:
+//
+//	api := router.Host("api.example.com")
+//	tenants := router.Host(":tenant.example.com")
+//	tenants.GET("/", func(ctx *Context) error { return ctx.String(200, ctx.Param("tenant")) })
+//
+// Hosts without parameters are tried before parameterized ones. Requests whose
+// host has no matching route fall back to the routes registered on the router itself.
+func (r *Router) Host(pattern string) *RouterGroup {
+	pattern = strings.ToLower(pattern)
+	for _, label := range strings.Split(pattern, ".") {
+		if label == "" || label == ":" {
+			err := fmt.Errorf("draupnir: invalid host pattern %q registered at %s", pattern, callerSite())
+			r.update(func(*routeTable) error { return err })
+			break
+		}
+	}
+
+	return &RouterGroup{
+		host:   pattern,
+		router: r,
+	}
+}
+
+// routesFor returns the routes of a host pattern, creating them on first use.
+// The empty host is the table's root.
+func (t *routeTable) routesFor(host string) *hostRoutes {
+	if host == "" {
+		return t.root
+	}
+
+	for _, h := range t.hosts {
+		if h.pattern == host {
+			return h
+		}
+	}
+
+	h := newHostRoutes(host)
+	if strings.Contains(host, ":") {
+		t.hosts = append(t.hosts, h)
+		return h
+	}
+
+	// Keep static hosts ahead of parameterized ones
+	i := 0
+	for i < len(t.hosts) && !strings.Contains(t.hosts[i].pattern, ":") {
+		i++
+	}
+	t.hosts = slices.Insert(t.hosts, i, h)
+	return h
+}
+
+// clone returns a copy of the table that can be modified without affecting t.
+// The trees of both tables share their nodes until either is modified.
+func (t *routeTable) clone() *routeTable {
+	c := &routeTable{
+		root:  t.root.clone(),
+		hosts: make([]*hostRoutes, len(t.hosts)),
+		names: t.names.Clone(),
+	}
+	for i, h := range t.hosts {
+		c.hosts[i] = h.clone()
+	}
+	return c
+}
+
+// clone returns a copy of the host routes that can be modified without affecting h.
+func (h *hostRoutes) clone() *hostRoutes {
+	return &hostRoutes{
+		pattern:          h.pattern,
+		routes:           h.routes.Clone(),
+		notFound:         h.notFound.Clone(),
+		methodNotAllowed: h.methodNotAllowed.Clone(),
+	}
+}
+
+// match finds the routes registered for the request host and the given path.
+// Host parameters come before path parameters in the returned params.
+// The params are appended to buf[:0], so a reused buffer avoids allocating.
+func (t *routeTable) match(req *http.Request, path string, buf []Param) (methodRoutes, []Param, bool) {
+	if len(t.hosts) > 0 {
+		host := requestHost(req)
+		for _, h := range t.hosts {
+			params, ok := matchHost(h.pattern, host, buf[:0])
+			if !ok {
+				continue
+			}
+			if val, params, found := h.routes.Match(path, params); found {
+				return val.(methodRoutes), params, true
+			}
+		}
+	}
+
+	val, params, found := t.root.routes.Match(path, buf[:0])
+	if !found {
+		return nil, params, false
+	}
+	return val.(methodRoutes), params, true
+}
+
+// matchFold is like match but compares static segments case-insensitively,
+// returning the path spelled as registered.
+func (t *routeTable) matchFold(req *http.Request, path string) (string, bool) {
+	if len(t.hosts) > 0 {
+		host := requestHost(req)
+		for _, h := range t.hosts {
+			if _, ok := matchHost(h.pattern, host, nil); !ok {
+				continue
+			}
+			if fixed, ok := h.routes.MatchFold(path); ok {
+				return fixed, true
+			}
+		}
+	}
+	return t.root.routes.MatchFold(path)
+}
+
+// fallback returns the NotFound or MethodNotAllowed handler of the group with the longest
+// prefix of path, looking at matching hosts before the root, or def if no group sets one.
+func (t *routeTable) fallback(req *http.Request, path string, methodNotAllowed bool, def route) route {
+	lookup := func(h *hostRoutes) (route, bool) {
+		handlers := h.notFound
+		if methodNotAllowed {
+			handlers = h.methodNotAllowed
+		}
+		val, _, found := handlers.Match(path, nil)
+		if !found {
+			return route{}, false
+		}
+		return val.(route), true
+	}
+
+	if len(t.hosts) > 0 {
+		host := requestHost(req)
+		for _, h := range t.hosts {
+			if _, ok := matchHost(h.pattern, host, nil); !ok {
+				continue
+			}
+			if handler, ok := lookup(h); ok {
+				return handler
+			}
+		}
+	}
+	if handler, ok := lookup(t.root); ok {
+		return handler
+	}
+	return def
+}
+
+// requestHost returns the lower-cased request host without its port.
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// matchHost matches a host against a pattern such as ":tenant.example.com",
+// appending the values of parameter labels to params.
+func matchHost(pattern, host string, params []Param) ([]Param, bool) {
+	for {
+		pi := strings.IndexByte(pattern, '.')
+		hi := strings.IndexByte(host, '.')
+
+		label, value := pattern, host
+		if pi >= 0 {
+			label = pattern[:pi]
+		}
+		if hi >= 0 {
+			value = host[:hi]
+		}
+
+		if label == "" {
+			// Only invalid patterns, kept in lenient mode, have empty labels
+			return params, false
+		}
+		if label[0] == ':' {
+			if value == "" {
+				return params, false
+			}
+			params = append(params, Param{Key: label[1:], Value: value})
+		} else if label != value {
+			return params, false
+		}
+
+		if pi < 0 || hi < 0 {
+			return params, pi < 0 && hi < 0
+		}
+		pattern, host = pattern[pi+1:], host[hi+1:]
+	}
+}
@@ -0,0 +1,64 @@
+package draupnir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostRouting(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	r.GET("/", func(c *Context) error { return c.String(http.StatusOK, "root") })
+	r.Host("api.example.com").GET("/", func(c *Context) error { return c.String(http.StatusOK, "api") })
+	tenants := r.Host(":tenant.example.com")
+	tenants.GET("/users/:id", func(c *Context) error {
+		return c.String(http.StatusOK, c.Param("tenant")+" "+c.Param("id"))
+	})
+
+	tests := []struct {
+		host, target string
+		code         int
+		body         string
+	}{
+		{"api.example.com", "/", http.StatusOK, "api"},
+		{"API.Example.com:8080", "/", http.StatusOK, "api"},
+		// Static hosts are tried before parameterized ones
+		{"acme.example.com", "/", http.StatusOK, "root"},
+		{"acme.example.com:443", "/users/7", http.StatusOK, "acme 7"},
+		{"a.b.example.com", "/users/7", http.StatusNotFound, ""},
+		// Other hosts fall back to the router's own routes
+		{"other.org", "/", http.StatusOK, "root"},
+		{"other.org", "/users/7", http.StatusNotFound, ""},
+		{"[::1]:8080", "/", http.StatusOK, "root"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s%s = %d %q, want %d %q", tt.host, tt.target, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestInvalidHostPattern(t *testing.T) {
+	r := New().WithRequestLogging(false).WithStrictRoutes(false)
+	r.Host("api..example.com")
+	if r.Err() == nil {
+		t.Error("Err() = nil, want an invalid host pattern error")
+	}
+
+	// Lenient mode keeps the group; its routes must never match
+	r.Host("a..b").GET("/", func(c *Context) error { return c.String(http.StatusOK, "invalid") })
+	r.GET("/", func(c *Context) error { return c.String(http.StatusOK, "root") })
+	for _, host := range []string{"a.x.b", "a..b", "a.b", "."} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != "root" {
+			t.Errorf("GET %s/ = %d %q, want the root route", host, w.Code, w.Body.String())
+		}
+	}
+}
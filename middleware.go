@@ -0,0 +1,107 @@
+package draupnir
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// middlewareEntry is a step of the Context pipeline together with the name of the
+// function it was built from, for introspection.
+type middlewareEntry struct {
+	handler HandlerFunc
+	name    string
+}
+
+func newMiddlewareEntry(m Middleware) middlewareEntry {
+	return middlewareEntry{HandlerFunc(m), getFunctionName(m)}
+}
+
+// middlewareChain is a list of middleware that may grow while requests are served.
+// Adding middleware publishes a new slice, so requests read the chain without locking.
+type middlewareChain struct {
+	mu      sync.Mutex
+	entries atomic.Pointer[[]middlewareEntry]
+}
+
+// load returns the middleware in the order they run. The slice must not be modified.
+func (mc *middlewareChain) load() []middlewareEntry {
+	if entries := mc.entries.Load(); entries != nil {
+		return *entries
+	}
+	return nil
+}
+
+// add appends middleware to the chain.
+func (mc *middlewareChain) add(m []Middleware) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	entries := slices.Clone(mc.load())
+	for _, m := range m {
+		entries = append(entries, newMiddlewareEntry(m))
+	}
+	mc.entries.Store(&entries)
+}
+
+// inherit starts the chain with the middleware currently in parent.
+func (mc *middlewareChain) inherit(parent *middlewareChain) {
+	entries := parent.load()
+	mc.entries.Store(&entries)
+}
+
+// WrapMiddleware adapts net/http middleware of the standard func(http.Handler) http.Handler
+// shape, so that ecosystem middleware can be used with Use:
+//
+//	router.Use(draupnir.WrapMiddleware(handlers.CompressHandler))
+//
+// The rest of the pipeline runs when the middleware calls its next handler, with the
+// writer and request it passes on; if it does not, the pipeline is aborted.
+// The original request is restored afterwards, unless the rest of the pipeline
+// replaced it, as the first Set does.
+func WrapMiddleware(m func(http.Handler) http.Handler) Middleware {
+	return func(c *Context) error {
+		w, req := c.Writer, c.Request
+		var next *http.Request
+		defer func() {
+			c.Writer = w
+			if next == nil || c.Request == next {
+				c.Request = req
+			}
+		}()
+
+		var err error
+		called := false
+		m(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called = true
+			c.Writer, c.Request, next = w, req, req
+			err = c.Next()
+		})).ServeHTTP(w, req)
+
+		if !called {
+			c.Abort()
+		}
+		return err
+	}
+}
+
+// WrapHandlerFunc adapts net/http middleware of the func(http.HandlerFunc) http.HandlerFunc
+// shape. See WrapMiddleware.
+func WrapHandlerFunc(m func(http.HandlerFunc) http.HandlerFunc) Middleware {
+	return WrapMiddleware(func(next http.Handler) http.Handler {
+		return m(next.ServeHTTP)
+	})
+}
+
+// AsMiddleware returns the router as standard net/http middleware. Requests matching
+// one of its routes are served by the router; the others are passed to next, without
+// trailing-slash or fixed-path redirects:
+//
+//	http.ListenAndServe(":8080", api.AsMiddleware()(legacyMux))
+func (r *Router) AsMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.serve(w, req, next)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package draupnir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// trace returns a middleware recording its name before and after the rest of the pipeline.
+func trace(order *[]string, name string) Middleware {
+	return func(c *Context) error {
+		*order = append(*order, name)
+		err := c.Next()
+		*order = append(*order, name+" done")
+		return err
+	}
+}
+
+func serve(r *Router, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	r := New().WithRequestLogging(false)
+	api := r.Group("/api").Use(trace(&order, "group"))
+	r.Use(trace(&order, "global 1"), trace(&order, "global 2"))
+	v1 := api.Group("/v1").Use(trace(&order, "subgroup"))
+	v1.GET("/users", func(c *Context) error {
+		order = append(order, "handler")
+		return nil
+	}, trace(&order, "route 1"), trace(&order, "route 2"))
+
+	serve(r, http.MethodGet, "/api/v1/users")
+
+	want := []string{
+		"global 1", "global 2", "group", "subgroup", "route 1", "route 2",
+		"handler",
+		"route 2 done", "route 1 done", "subgroup done", "group done", "global 2 done", "global 1 done",
+	}
+	if !slices.Equal(order, want) {
+		t.Errorf("order = %q, want %q", order, want)
+	}
+}
+
+func TestMiddlewareWithoutNext(t *testing.T) {
+	var order []string
+	r := New().WithRequestLogging(false)
+	r.Use(func(c *Context) error {
+		order = append(order, "before")
+		return nil
+	})
+	r.GET("/", func(c *Context) error {
+		order = append(order, "handler")
+		return nil
+	})
+
+	serve(r, http.MethodGet, "/")
+
+	if want := []string{"before", "handler"}; !slices.Equal(order, want) {
+		t.Errorf("order = %q, want %q", order, want)
+	}
+}
+
+func TestMiddlewareAbort(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	r.Use(func(c *Context) error {
+		return c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	})
+	r.GET("/", func(c *Context) error {
+		t.Error("handler ran after Abort")
+		return nil
+	})
+
+	if w := serve(r, http.MethodGet, "/"); w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestMiddlewareRunsForFallbacks(t *testing.T) {
+	var order []string
+	r := New().WithRequestLogging(false)
+	r.Use(trace(&order, "global"))
+	r.Group("/api").Use(trace(&order, "group")).NotFound(func(c *Context) error {
+		order = append(order, "not found")
+		return nil
+	})
+
+	serve(r, http.MethodGet, "/api/missing")
+
+	if want := []string{"global", "group", "not found", "group done", "global done"}; !slices.Equal(order, want) {
+		t.Errorf("order = %q, want %q", order, want)
+	}
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	var order []string
+	header := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			order = append(order, "net/http")
+			w.Header().Set("X-Wrapped", "yes")
+			next.ServeHTTP(w, req)
+		})
+	}
+	legacy := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			order = append(order, "legacy")
+			next(w, req)
+		}
+	}
+
+	r := New().WithRequestLogging(false)
+	r.Use(WrapMiddleware(header), trace(&order, "context"), WrapHandlerFunc(legacy))
+	r.GET("/", func(c *Context) error {
+		c.Set("user", "gopher")
+		return c.String(http.StatusOK, c.GetString("user"))
+	})
+
+	w := serve(r, http.MethodGet, "/")
+
+	if want := []string{"net/http", "context", "legacy", "context done"}; !slices.Equal(order, want) {
+		t.Errorf("order = %q, want %q", order, want)
+	}
+	if w.Header().Get("X-Wrapped") != "yes" || w.Body.String() != "gopher" {
+		t.Errorf("got header %q and body %q", w.Header().Get("X-Wrapped"), w.Body.String())
+	}
+}
+
+func TestWrapMiddlewareWithoutNext(t *testing.T) {
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+		})
+	}
+
+	r := New().WithRequestLogging(false)
+	r.GET("/", func(c *Context) error {
+		t.Error("handler ran after net/http middleware stopped the request")
+		return nil
+	}, WrapMiddleware(deny))
+
+	if w := serve(r, http.MethodGet, "/"); w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestWrapMiddlewareKeepsStore(t *testing.T) {
+	userKey := NewKey[string]("user")
+	var outer, handled any
+	passThrough := func(next http.Handler) http.Handler { return next }
+
+	r := New().WithRequestLogging(false)
+	r.ErrorHandler(func(c *Context, err error) {
+		handled = c.Request.Context().Value(userKey)
+		DefaultErrorHandler(c, err)
+	})
+	r.Use(func(c *Context) error {
+		err := c.Next()
+		outer = c.Request.Context().Value(userKey)
+		return err
+	}, WrapMiddleware(passThrough))
+	r.GET("/", func(c *Context) error {
+		userKey.Set(c, "gopher")
+		return NewHTTPError(http.StatusTeapot, "teapot")
+	})
+
+	if w := serve(r, http.MethodGet, "/"); w.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTeapot)
+	}
+	if outer != "gopher" || handled != "gopher" {
+		t.Errorf("request context value = %v in outer middleware and %v in the error handler, want gopher", outer, handled)
+	}
+}
+
+func TestAsMiddleware(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	r.GET("/api/status", func(c *Context) error {
+		return c.String(http.StatusOK, "router")
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("next"))
+	})
+	h := r.AsMiddleware()(next)
+
+	for target, want := range map[string]string{"/api/status": "router", "/legacy": "next"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Body.String() != want {
+			t.Errorf("GET %s = %q, want %q", target, w.Body.String(), want)
+		}
+	}
+}
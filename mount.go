@@ -0,0 +1,104 @@
+package draupnir
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// methodAny is the methodRoutes key of routes that accept every HTTP method.
+const methodAny = "*"
+
+// mountParam is the catch-all parameter holding the path below a mount prefix.
+const mountParam = "mount"
+
+// Mount forwards every request under prefix, whatever its method, to h with the prefix
+// stripped from the path. h may be another *Router, which keeps its own middleware and
+// 404 handling, or any http.Handler that expects to own a path tree:
+//
+//	router.Mount("/billing", billing.Router())
+//
+// The prefix itself is forwarded as "/". Global middleware of the router runs
+// before h, followed by the given middlewares.
+func (r *Router) Mount(prefix string, h http.Handler, middlewares ...Middleware) *Route {
+	return &Route{r, r.mount(nil, prefix, h, true, middlewares)}
+}
+
+// MountNoStrip is like Mount but forwards requests with their path unchanged, for
+// handlers that expect the full path:
+//
+//	router.MountNoStrip("/debug/pprof", http.HandlerFunc(pprof.Index))
+func (r *Router) MountNoStrip(prefix string, h http.Handler, middlewares ...Middleware) *Route {
+	return &Route{r, r.mount(nil, prefix, h, false, middlewares)}
+}
+
+// Mount forwards every request under the group prefix plus prefix to h.
+// Group middleware runs before h. See Router.Mount.
+func (rg *RouterGroup) Mount(prefix string, h http.Handler, middlewares ...Middleware) *GroupRoute {
+	return &GroupRoute{rg, rg.router.mount(rg, prefix, h, true, middlewares)}
+}
+
+// MountNoStrip forwards every request under the group prefix plus prefix to h with
+// its path unchanged. See Router.MountNoStrip.
+func (rg *RouterGroup) MountNoStrip(prefix string, h http.Handler, middlewares ...Middleware) *GroupRoute {
+	return &GroupRoute{rg, rg.router.mount(rg, prefix, h, false, middlewares)}
+}
+
+// mount registers the routes forwarding a prefix and its subtree to h in a single
+// registration, so that Name and Meta apply to the whole mount.
+func (r *Router) mount(rg *RouterGroup, prefix string, h http.Handler, strip bool, middlewares []Middleware) []route {
+	base := newRoutes(rg, []string{methodAny}, prefix, mountHandler(h, strip), middlewares)[0]
+	base.handlerName = mountedName(h)
+	base.mount = strings.TrimSuffix(base.pattern, "/")
+	if base.mount == "" {
+		base.mount = "/"
+	}
+
+	var routes []route
+	for _, pattern := range subtreePatterns(base.mount) {
+		rt := base
+		rt.pattern = pattern
+		routes = append(routes, rt)
+	}
+	return r.addRoutes(routes)
+}
+
+// mountedName returns the name of a mounted handler, for introspection.
+func mountedName(h http.Handler) string {
+	if f, ok := h.(http.HandlerFunc); ok {
+		return getFunctionName(f)
+	}
+	return fmt.Sprintf("%T", h)
+}
+
+// subtreePatterns returns the patterns covering a prefix and its subtree.
+func subtreePatterns(prefix string) []string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return []string{"/*" + mountParam}
+	}
+	return []string{prefix, prefix + "/*" + mountParam}
+}
+
+// mountHandler forwards requests to h, with the mount prefix stripped from the path
+// when strip is set.
+func mountHandler(h http.Handler, strip bool) HandlerFunc {
+	return func(c *Context) error {
+		if !strip {
+			h.ServeHTTP(c.Writer, c.Request)
+			return nil
+		}
+
+		u := new(url.URL)
+		*u = *c.Request.URL
+		u.Path = "/" + c.Param(mountParam)
+		u.RawPath = ""
+
+		req := new(http.Request)
+		*req = *c.Request
+		req.URL = u
+		h.ServeHTTP(c.Writer, req)
+		return nil
+	}
+}
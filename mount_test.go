@@ -0,0 +1,113 @@
+package draupnir
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMountRouter(t *testing.T) {
+	sub := New().WithRequestLogging(false)
+	sub.GET("/", func(c *Context) error { return c.String(http.StatusOK, "billing index") })
+	sub.GET("/invoices/:id", func(c *Context) error { return c.String(http.StatusOK, "invoice "+c.Param("id")) })
+	sub.POST("/invoices", func(c *Context) error { return c.String(http.StatusCreated, "created") })
+	sub.NotFound(func(c *Context) error { return c.String(http.StatusNotFound, "billing not found") })
+
+	r := New().WithRequestLogging(false)
+	r.Group("/api").Mount("/billing", sub)
+
+	tests := []struct {
+		method, target string
+		code           int
+		body           string
+	}{
+		{http.MethodGet, "/api/billing", http.StatusOK, "billing index"},
+		{http.MethodGet, "/api/billing/", http.StatusOK, "billing index"},
+		{http.MethodGet, "/api/billing/invoices/7", http.StatusOK, "invoice 7"},
+		{http.MethodPost, "/api/billing/invoices", http.StatusCreated, "created"},
+		{http.MethodGet, "/api/billing/missing", http.StatusNotFound, "billing not found"},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.target)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.target, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestMountHandler(t *testing.T) {
+	var paths []string
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery)
+	})
+
+	r := New().WithRequestLogging(false)
+	r.Mount("/static", h)
+	r.MountNoStrip("/full", h)
+
+	targets := []struct{ method, target, want string }{
+		{http.MethodGet, "/static", "GET /?"},
+		{http.MethodGet, "/static/css/site.css?v=2", "GET /css/site.css?v=2"},
+		{http.MethodDelete, "/static/a", "DELETE /a?"},
+		{http.MethodGet, "/full", "GET /full?"},
+		{http.MethodPut, "/full/a/b", "PUT /full/a/b?"},
+	}
+	for _, tt := range targets {
+		paths = nil
+		if w := serve(r, tt.method, tt.target); w.Code != http.StatusOK {
+			t.Errorf("%s %s = %d, want 200", tt.method, tt.target, w.Code)
+		}
+		if len(paths) != 1 || paths[0] != tt.want {
+			t.Errorf("%s %s forwarded %q, want %q", tt.method, tt.target, paths, tt.want)
+		}
+	}
+
+	if w := serve(r, http.MethodGet, "/staticx"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /staticx = %d, want 404", w.Code)
+	}
+}
+
+func TestMountNoStripPprof(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	r.MountNoStrip("/debug/pprof", http.HandlerFunc(pprof.Index))
+
+	w := serve(r, http.MethodGet, "/debug/pprof/goroutine?debug=1")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "goroutine profile:") {
+		t.Errorf("GET /debug/pprof/goroutine = %d %.40q, want the goroutine profile", w.Code, w.Body.String())
+	}
+}
+
+func TestMountNameAndRoutes(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	r := New().WithRequestLogging(false)
+	r.Mount("/n", h).Name("n").Meta("kind", "mount")
+	r.Group("/api").MountNoStrip("/docs/", h).Name("docs")
+	r.GET("/users", func(c *Context) error { return nil })
+
+	for name, want := range map[string]string{"n": "/n", "docs": "/api/docs"} {
+		if got, err := r.URL(name); err != nil || got != want {
+			t.Errorf("URL(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	want := []string{"ANY /api/docs", "ANY /n", "GET /users"}
+	if got := r.ListRoutes(); !slices.Equal(got, want) {
+		t.Errorf("ListRoutes() = %q, want %q", got, want)
+	}
+	for _, info := range r.Routes() {
+		if info.Pattern == "/n" && (info.Name != "n" || info.Meta["kind"] != "mount" || !strings.HasSuffix(info.Handler, "TestMountNameAndRoutes.func1")) {
+			t.Errorf("Routes() describes the mount as %+v", info)
+		}
+	}
+
+	root := New().WithRequestLogging(false)
+	root.Mount("/", h).Name("root")
+	if got := root.ListRoutes(); !slices.Equal(got, []string{"ANY /"}) {
+		t.Errorf("ListRoutes() = %q with a root mount, want [\"ANY /\"]", got)
+	}
+	if got, err := root.URL("root"); err != nil || got != "/" {
+		t.Errorf("URL(root) = %q, %v, want /", got, err)
+	}
+}
@@ -0,0 +1,188 @@
+package draupnir
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrPartTooLarge is the cause of the 413 error returned when a multipart part exceeds
+// its MultipartLimits.
+var ErrPartTooLarge = errors.New("draupnir: multipart part too large")
+
+// MultipartLimits bounds the parts read by MultipartStream. Zero fields are unlimited;
+// the size of the whole body is still bounded by the body size limit of the route.
+type MultipartLimits struct {
+	MaxFieldSize int64 // bytes of a form field part
+	MaxFileSize  int64 // bytes of a file part
+	MaxParts     int   // number of parts
+}
+
+// MultipartPart is a part of a multipart form read by MultipartStream.
+// Reading it fails with a 413 error once it exceeds its size limit.
+type MultipartPart struct {
+	*multipart.Part
+	limit     int64 // size limit, 0 for none
+	remaining int64 // bytes left before the limit, -1 once exceeded
+}
+
+// IsFile reports whether the part is a file upload rather than a form field.
+func (p *MultipartPart) IsFile() bool {
+	return p.FileName() != ""
+}
+
+func (p *MultipartPart) Read(b []byte) (int, error) {
+	if p.limit <= 0 {
+		return p.Part.Read(b)
+	}
+	if p.remaining < 0 {
+		return 0, p.tooLarge()
+	}
+	// Read one byte past the limit to tell a part of exactly the limit from a larger one.
+	if int64(len(b)) > p.remaining+1 {
+		b = b[:p.remaining+1]
+	}
+	n, err := p.Part.Read(b)
+	if int64(n) <= p.remaining {
+		p.remaining -= int64(n)
+		return n, err
+	}
+	n = int(p.remaining)
+	p.remaining = -1
+	return n, p.tooLarge()
+}
+
+func (p *MultipartPart) tooLarge() error {
+	return NewHTTPError(http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("part %q exceeds %d bytes", p.FormName(), p.limit)).WithCause(ErrPartTooLarge)
+}
+
+// MultipartStream iterates over the parts of a multipart form as they arrive, without
+// buffering the body like FormFile and FormValue do. A part is only valid until the next
+// iteration; parts left unread are skipped, and still count against the limits:
+//
+//	for part, err := range c.MultipartStream(limits) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Errors end the iteration. Exceeded limits are reported as 413 errors and malformed
+// bodies as 400 errors.
+func (c *Context) MultipartStream(limits MultipartLimits) iter.Seq2[*MultipartPart, error] {
+	return func(yield func(*MultipartPart, error) bool) {
+		if c.mediaType() != MIMEMultipartForm {
+			yield(nil, unsupportedMediaType(MIMEMultipartForm))
+			return
+		}
+		mr, err := c.Request.MultipartReader()
+		if err != nil {
+			yield(nil, bodyError("invalid multipart body", err))
+			return
+		}
+
+		for n := 0; ; n++ {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, bodyError("invalid multipart body", err))
+				return
+			}
+			if limits.MaxParts > 0 && n >= limits.MaxParts {
+				part.Close()
+				yield(nil, NewHTTPError(http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("more than %d parts", limits.MaxParts)).WithCause(ErrPartTooLarge))
+				return
+			}
+
+			p := &MultipartPart{Part: part, limit: limits.MaxFieldSize}
+			if p.IsFile() {
+				p.limit = limits.MaxFileSize
+			}
+			p.remaining = p.limit
+
+			more := yield(p, nil)
+			// Drain the part through its limit, so that skipped parts are bounded too.
+			_, err = io.Copy(io.Discard, p)
+			part.Close()
+			if !more {
+				return
+			}
+			if err != nil {
+				if !errors.Is(err, ErrPartTooLarge) {
+					err = bodyError("invalid multipart body", err)
+				}
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// SaveUploadedFile writes an uploaded file to dst. When dst is an existing directory
+// or ends with a path separator, the file is saved in it under its sanitized client
+// file name. Missing parent directories are created.
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	if info, err := os.Stat(dst); err == nil && info.IsDir() || dst != "" && os.IsPathSeparator(dst[len(dst)-1]) {
+		dst = filepath.Join(dst, SanitizeFilename(fh.Filename))
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// SanitizeFilename turns a client-supplied file name into a safe base name: directories,
+// control characters and characters reserved on common file systems are removed, as are
+// leading dots, so that the name cannot escape a directory or be hidden. An unusable
+// name becomes "file".
+func SanitizeFilename(name string) string {
+	// Clients on Windows may send full paths with backslashes.
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = name[strings.LastIndex(name, "/")+1:]
+
+	name = strings.Map(func(r rune) rune {
+		if r == utf8.RuneError || unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(name, ". ")
+	name = strings.TrimRight(name, ". ")
+
+	const maxLen = 255
+	for len(name) > maxLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
@@ -0,0 +1,153 @@
+package draupnir
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormFile(t *testing.T) {
+	var errs []error
+	var names []string
+	r := New().WithRequestLogging(false)
+	r.POST("/", func(c *Context) error {
+		fh, err := c.FormFile("avatar")
+		errs = append(errs, err)
+		if fh != nil {
+			names = append(names, fh.Filename)
+		}
+		_, err = c.FormFile("missing")
+		errs = append(errs, err)
+		return nil
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, "/", formPart{"avatar", "me.png", "png"}, formPart{"name", "", "bob"}))
+	if errs[0] != nil || len(names) != 1 || names[0] != "me.png" {
+		t.Errorf("FormFile(avatar) = %v, %v, want me.png", names, errs[0])
+	}
+	if !errors.Is(errs[1], http.ErrMissingFile) {
+		t.Errorf("FormFile(missing) error = %v, want http.ErrMissingFile", errs[1])
+	}
+
+	// A body that is not multipart has no files either
+	errs = nil
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("a=1"))
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if !errors.Is(errs[0], http.ErrMissingFile) {
+		t.Errorf("FormFile on a urlencoded body = %v, want http.ErrMissingFile", errs[0])
+	}
+}
+
+func TestMultipartStream(t *testing.T) {
+	limits := MultipartLimits{MaxFieldSize: 8, MaxFileSize: 16, MaxParts: 3}
+	var read []string
+	r := New().WithRequestLogging(false)
+	r.POST("/read", func(c *Context) error {
+		for part, err := range c.MultipartStream(limits) {
+			if err != nil {
+				return err
+			}
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return err
+			}
+			read = append(read, part.FormName()+"="+string(data))
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+	r.POST("/skip", func(c *Context) error {
+		for _, err := range c.MultipartStream(limits) {
+			if err != nil {
+				return err
+			}
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		parts  []formPart
+		code   int
+	}{
+		{"within limits", "/read", []formPart{{"a", "", "12345678"}, {"f", "f.txt", strings.Repeat("x", 16)}}, http.StatusOK},
+		{"field too large", "/read", []formPart{{"a", "", "123456789"}}, http.StatusRequestEntityTooLarge},
+		{"file too large", "/read", []formPart{{"a", "", "1"}, {"f", "f.txt", strings.Repeat("x", 17)}}, http.StatusRequestEntityTooLarge},
+		{"skipped file too large", "/skip", []formPart{{"f", "f.txt", strings.Repeat("x", 1<<16)}}, http.StatusRequestEntityTooLarge},
+		{"too many parts", "/skip", []formPart{{"a", "", "1"}, {"b", "", "2"}, {"c", "", "3"}, {"d", "", "4"}}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		read = nil
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, multipartRequest(t, tt.target, tt.parts...))
+		if w.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, w.Code, tt.code, w.Body.String())
+		}
+	}
+
+	read = nil
+	r.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, "/read", formPart{"a", "", "1"}, formPart{"f", "f.txt", "data"}))
+	if want := []string{"a=1", "f=data"}; strings.Join(read, ",") != strings.Join(want, ",") {
+		t.Errorf("read %q, want %q", read, want)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/read", strings.NewReader("{}"))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("JSON body: status = %d, want 415", w.Code)
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"photo.jpg", "photo.jpg"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\bob\report.pdf`, "report.pdf"},
+		{`..\..\boot.ini`, "boot.ini"},
+		{"..", "file"},
+		{".", "file"},
+		{"", "file"},
+		{"dir/", "file"},
+		{".hidden", "hidden"},
+		{`a<b>c:d"e|f?g*h.txt`, "abcdefgh.txt"},
+		{"new\x00line\n.txt", "newline.txt"},
+		{"trailing. . ", "trailing"},
+		{"bad\xffutf8.txt", "badutf8.txt"},
+		{strings.Repeat("é", 200), strings.Repeat("é", 127)},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.name); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSaveUploadedFile(t *testing.T) {
+	dir := t.TempDir()
+	r := New().WithRequestLogging(false)
+	r.POST("/", func(c *Context) error {
+		fh, err := c.FormFile("f")
+		if err != nil {
+			return err
+		}
+		return c.SaveUploadedFile(fh, dir+string(filepath.Separator))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, multipartRequest(t, "/", formPart{"f", "../../escape.txt", "content"}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", w.Code, w.Body.String())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "escape.txt"))
+	if err != nil || string(data) != "content" {
+		t.Errorf("saved file = %q, %v, want \"content\" inside the directory", data, err)
+	}
+}
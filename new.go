@@ -48,15 +48,40 @@ func NewRateLimiter(maxTokens int, refillInterval time.Duration) *RateLimiter {
 	return rl
 }
 
+// defaultMultipartMemory is the part of a multipart form kept in memory, 32 MB.
+const defaultMultipartMemory = 32 << 20
+
 func New() *Router {
 	r := &Router{
-		staticRoutes:  tree.New(),
-		dynamicRoutes: make([]route, 0),
-		middlewares:   []Middleware{},
+		strict:      true,
+		autoHEAD:    true,
+		autoOPTIONS: true,
+
+		redirectTrailingSlash: true,
+		unescapePathValues:    true,
+		requestLogging:        true,
+		validator:             NewValidator(),
+		codec:                 &JSONCodec{EscapeHTML: true},
+		multipartMemory:       defaultMultipartMemory,
 	}
+	r.pool.New = func() any { return new(Context) }
+	r.table.Store(&routeTable{
+		root:  newHostRoutes(""),
+		names: tree.New(),
+	})
 	return r
 }
 
+// newHostRoutes creates the empty route trees of a host pattern.
+func newHostRoutes(pattern string) *hostRoutes {
+	return &hostRoutes{
+		pattern:          pattern,
+		routes:           tree.New(),
+		notFound:         tree.New(),
+		methodNotAllowed: tree.New(),
+	}
+}
+
 // NewWorkerPool creates a new worker pool with the given size.
 // It sets the channel buffer to size*10 to allow bursts of tasks.
 func NewWorkerPool(size int) *WorkerPool {
@@ -0,0 +1,164 @@
+package draupnir
+
+import (
+	"bufio"
+	"math"
+	"net"
+	"net/http"
+)
+
+// abortIndex is the handler index of an aborted pipeline.
+const abortIndex = math.MaxInt / 2
+
+// Next runs the remaining handlers of the pipeline before returning to the calling
+// middleware. If a handler returns an error, the pipeline is aborted and Next returns it.
+func (c *Context) Next() error {
+	c.index++
+	for c.index < len(c.handlers) {
+		if err := c.handlers[c.index](c); err != nil {
+			c.Abort()
+			return err
+		}
+		c.index++
+	}
+	return nil
+}
+
+// Abort prevents the remaining handlers of the pipeline from running.
+// The handlers already running, such as the middleware calling Next, still complete.
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted reports whether the pipeline was aborted.
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus aborts the pipeline and writes the status code.
+func (c *Context) AbortWithStatus(code int) {
+	c.Abort()
+	c.Writer.WriteHeader(code)
+	c.statusCode = code
+}
+
+// AbortWithStatusJSON aborts the pipeline and sends obj as a JSON response.
+func (c *Context) AbortWithStatusJSON(code int, obj any) error {
+	c.Abort()
+	return c.JSON(code, obj)
+}
+
+// handle runs the handler of rt behind the global middleware, the middleware of its
+// group and its own middleware, then renders any error returned by the pipeline.
+func (r *Router) handle(c *Context, rt route) {
+	c.pattern = rt.pattern
+	c.handlers = c.handlers[:0]
+	for _, m := range r.middlewares.load() {
+		c.handlers = append(c.handlers, m.handler)
+	}
+	if rt.group != nil {
+		for _, m := range rt.group.middlewares.load() {
+			c.handlers = append(c.handlers, m.handler)
+		}
+	}
+	for _, m := range rt.middlewares {
+		c.handlers = append(c.handlers, m.handler)
+	}
+	c.handlers = append(c.handlers, rt.handler)
+
+	if limit := r.bodyLimit(rt); limit > 0 && c.Request.Body != nil && c.Request.Body != http.NoBody {
+		c.Request.Body = http.MaxBytesReader(c.writer.ResponseWriter, c.Request.Body, limit)
+	}
+
+	if err := c.Next(); err != nil {
+		r.handleError(c, err)
+	}
+}
+
+// bodyLimit returns the request body limit of rt: its own, its group's or the router's.
+// It is 0 or negative when the body is unlimited.
+func (r *Router) bodyLimit(rt route) int64 {
+	limit := r.maxBodySize
+	if rt.group != nil {
+		if n := rt.group.maxBodySize.Load(); n != 0 {
+			limit = n
+		}
+	}
+	if rt.maxBodySize != 0 {
+		limit = rt.maxBodySize
+	}
+	return limit
+}
+
+// reset prepares a pooled Context for a request, keeping the capacity of its
+// handler and parameter slices. The store is dropped rather than cleared, since
+// the request context of the previous request may still refer to it.
+func (c *Context) reset(r *Router, w http.ResponseWriter, req *http.Request) {
+	c.writer = responseWriter{ResponseWriter: w}
+	c.Writer = &c.writer
+	c.Request = req
+	c.path = req.URL.Path
+	c.method = req.Method
+	c.pattern = ""
+	c.router = r
+	c.handlers = c.handlers[:0]
+	c.index = -1
+	c.params = c.params[:0]
+	c.store = nil
+	c.bodyParsed = false
+	c.queryParsed = false
+	c.formParsed = false
+	c.multipartParsed = false
+	c.query = nil
+	c.formValues = nil
+	c.multipartForm = nil
+	c.statusCode = 0
+}
+
+// responseWriter records whether the response has been started, so that errors
+// returned after writing are not rendered over the response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hj.Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// written reports whether the status line has been sent.
+func (w *responseWriter) written() bool {
+	return w.status != 0
+}
@@ -0,0 +1,100 @@
+package draupnir
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimitPrecedence(t *testing.T) {
+	read := func(c *Context) error {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "ok")
+	}
+
+	r := New().WithRequestLogging(false).WithMaxBodySize(10)
+	r.POST("/router", read)
+	r.POST("/route", read).MaxBodySize(5)
+	r.POST("/route/unlimited", read).MaxBodySize(-1)
+
+	g := r.Group("/group").WithMaxBodySize(20)
+	g.POST("/", read)
+	g.POST("/route", read).MaxBodySize(30)
+	g.Group("/sub").POST("/", read)
+	r.Group("/open").WithMaxBodySize(-1).POST("/", read)
+
+	tests := []struct {
+		target string
+		limit  int // largest accepted body, -1 for none
+	}{
+		{"/router", 10},
+		{"/route", 5},
+		{"/route/unlimited", -1},
+		{"/group/", 20},
+		{"/group/route", 30},
+		{"/group/sub/", 20},
+		{"/open/", -1},
+	}
+	for _, tt := range tests {
+		sizes := []int{tt.limit, tt.limit + 1}
+		if tt.limit < 0 {
+			sizes = []int{1 << 20}
+		}
+		for _, size := range sizes {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, bodyRequest(http.MethodPost, tt.target, MIMETextPlain, strings.Repeat("x", size)))
+			want := http.StatusOK
+			if tt.limit >= 0 && size > tt.limit {
+				want = http.StatusRequestEntityTooLarge
+			}
+			if w.Code != want {
+				t.Errorf("POST %s with %d bytes = %d, want %d", tt.target, size, w.Code, want)
+			}
+		}
+	}
+}
+
+func TestBodyLimitBinding(t *testing.T) {
+	type upload struct {
+		Name string `json:"name" form:"name"`
+	}
+	r := New().WithRequestLogging(false).WithMaxBodySize(64)
+	r.POST("/bind", func(c *Context) error {
+		var u upload
+		if err := c.Bind(&u); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, u.Name)
+	})
+	r.POST("/file", func(c *Context) error {
+		if _, err := c.FormFile("f"); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	large := strings.Repeat("x", 100)
+	tests := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"small json", bodyRequest(http.MethodPost, "/bind", MIMEApplicationJSON, `{"name":"bob"}`), http.StatusOK},
+		{"large json", bodyRequest(http.MethodPost, "/bind", MIMEApplicationJSON, `{"name":"`+large+`"}`), http.StatusRequestEntityTooLarge},
+		{"large xml", bodyRequest(http.MethodPost, "/bind", MIMEApplicationXML, `<upload><name>`+large+`</name></upload>`), http.StatusRequestEntityTooLarge},
+		{"large form", bodyRequest(http.MethodPost, "/bind", MIMEApplicationForm, "name="+large), http.StatusRequestEntityTooLarge},
+		{"large multipart", multipartRequest(t, "/bind", formPart{"name", "", large}), http.StatusRequestEntityTooLarge},
+		{"large file", multipartRequest(t, "/file", formPart{"f", "f.txt", large}), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, tt.req)
+		if w.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, w.Code, tt.code, strings.TrimSpace(w.Body.String()))
+		}
+	}
+}
@@ -1,26 +1,38 @@
 package draupnir
 
 import (
-	"context"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
+	pathpkg "path"
 	"reflect"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/kashari/draupnir/tree"
 	"github.com/kashari/golog"
 )
 
 // RouterGroup represents a group of routes with a common prefix and middleware.
 type RouterGroup struct {
+	host        string // host pattern, empty for any host
 	prefix      string
-	middlewares []Middleware
+	middlewares middlewareChain
+	maxBodySize atomic.Int64 // request body limit of the group's routes, 0 to use the router's
 	router      *Router
 }
 
-// Use adds a middleware to the chain.
-func (r *Router) Use(m Middleware) *Router {
-	r.middlewares = append(r.middlewares, m)
+// Use adds middleware to the chain.
+// Global middleware runs for every route and for the NotFound and MethodNotAllowed
+// handlers, before the group and route middleware.
+func (r *Router) Use(m ...Middleware) *Router {
+	r.middlewares.add(m)
 	return r
 }
 
@@ -28,113 +40,113 @@ func (r *Router) Use(m Middleware) *Router {
 // All routes registered on this group will be prefixed with the given prefix.
 func (r *Router) Group(prefix string) *RouterGroup {
 	return &RouterGroup{
-		prefix:      prefix,
-		middlewares: make([]Middleware, 0),
-		router:      r,
+		prefix: prefix,
+		router: r,
 	}
 }
 
 // Use adds middleware to the route group.
-// This middleware will be applied to all routes in this group.
-func (rg *RouterGroup) Use(m Middleware) *RouterGroup {
-	rg.middlewares = append(rg.middlewares, m)
+// This middleware will be applied to all routes in this group, after the global
+// middleware and the middleware of the parent groups.
+func (rg *RouterGroup) Use(m ...Middleware) *RouterGroup {
+	rg.middlewares.add(m)
 	return rg
 }
 
 // Group creates a sub-group with an additional prefix.
 // The new group will inherit the current group's prefix and middleware.
 func (rg *RouterGroup) Group(prefix string) *RouterGroup {
-	return &RouterGroup{
-		prefix:      rg.prefix + prefix,
-		middlewares: append([]Middleware{}, rg.middlewares...), // Copy middlewares
-		router:      rg.router,
+	sub := &RouterGroup{
+		host:   rg.host,
+		prefix: rg.prefix + prefix,
+		router: rg.router,
 	}
+	sub.middlewares.inherit(&rg.middlewares)
+	sub.maxBodySize.Store(rg.maxBodySize.Load())
+	return sub
 }
 
 // HandleFunc registers a route using a Context-based handler in the group.
-func (rg *RouterGroup) HandleFunc(method, pattern string, handler func(*Context)) *RouterGroup {
-	fullPattern := rg.prefix + pattern
-
-	// Create a handler that applies group middlewares
-	wrappedHandler := func(w http.ResponseWriter, req *http.Request) {
-		ctx := &Context{Writer: w, Request: req}
+// The route middlewares run after the group middlewares.
+func (rg *RouterGroup) HandleFunc(method, pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.Match([]string{method}, pattern, handler, middlewares...)
+}
 
-		// Create a handler function that applies group middlewares
-		finalHandler := func(c *Context) {
-			handler(c)
-		}
+// Match registers handler for each of methods in the group.
+// See Router.Match.
+func (rg *RouterGroup) Match(methods []string, pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return &GroupRoute{rg, rg.router.addRoutes(newRoutes(rg, methods, pattern, handler, middlewares))}
+}
 
-		// Apply group middlewares in reverse order
-		for i := len(rg.middlewares) - 1; i >= 0; i-- {
-			middleware := rg.middlewares[i]
-			currentHandler := finalHandler
-			finalHandler = func(c *Context) {
-				// Convert Context-based handler to http.HandlerFunc for middleware
-				httpHandler := func(w http.ResponseWriter, r *http.Request) {
-					currentHandler(c)
-				}
-				// Apply middleware and convert back
-				wrappedHttpHandler := middleware(httpHandler)
-				wrappedHttpHandler(w, req)
-			}
-		}
+// NotFound sets the handler for requests under the group prefix that match no route.
+// The group with the longest matching prefix wins; group middlewares apply.
+func (rg *RouterGroup) NotFound(handler HandlerFunc) *RouterGroup {
+	rg.router.setFallback(rg.host, rg.prefix, false, route{handler: handler, group: rg})
+	return rg
+}
 
-		finalHandler(ctx)
-	}
+// MethodNotAllowed sets the handler for requests under the group prefix whose path
+// matches a route but not its method. The Allow header is set before it runs.
+// The group with the longest matching prefix wins; group middlewares apply.
+func (rg *RouterGroup) MethodNotAllowed(handler HandlerFunc) *RouterGroup {
+	rg.router.setFallback(rg.host, rg.prefix, true, route{handler: handler, group: rg})
+	return rg
+}
 
-	rt := route{
-		method:  method,
-		pattern: fullPattern,
-		handler: wrappedHandler,
-	}
+// Remove unregisters the route for method and the group prefix plus pattern.
+// See Router.Remove.
+func (rg *RouterGroup) Remove(method, pattern string) bool {
+	return rg.router.remove(rg.host, method, rg.prefix+pattern)
+}
 
-	if !strings.ContainsAny(fullPattern, ":*") {
-		rg.router.staticRoutes.Insert(fullPattern, rt)
-	} else {
-		rg.router.dynamicRoutes = append(rg.router.dynamicRoutes, rt)
-	}
+// WithMaxBodySize limits the request bodies of the group's routes to n bytes,
+// replacing the router's limit. A negative n removes the limit.
+// Sub-groups created afterwards inherit the limit.
+func (rg *RouterGroup) WithMaxBodySize(n int64) *RouterGroup {
+	rg.maxBodySize.Store(n)
 	return rg
 }
 
 // HTTP method helpers for RouterGroup
-func (rg *RouterGroup) GET(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc("GET", pattern, handler)
+func (rg *RouterGroup) GET(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc("GET", pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) POST(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodPost, pattern, handler)
+func (rg *RouterGroup) POST(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc(http.MethodPost, pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) PUT(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodPut, pattern, handler)
+func (rg *RouterGroup) PUT(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc(http.MethodPut, pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) DELETE(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodDelete, pattern, handler)
+func (rg *RouterGroup) DELETE(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc(http.MethodDelete, pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) PATCH(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodPatch, pattern, handler)
+func (rg *RouterGroup) PATCH(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc(http.MethodPatch, pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) OPTIONS(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodOptions, pattern, handler)
+func (rg *RouterGroup) OPTIONS(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc(http.MethodOptions, pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) HEAD(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodHead, pattern, handler)
+func (rg *RouterGroup) HEAD(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc(http.MethodHead, pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) TRACE(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodTrace, pattern, handler)
+func (rg *RouterGroup) TRACE(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc(http.MethodTrace, pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) CONNECT(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodConnect, pattern, handler)
+func (rg *RouterGroup) CONNECT(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.HandleFunc(http.MethodConnect, pattern, handler, middlewares...)
 }
 
-func (rg *RouterGroup) ANY(pattern string, handler func(*Context)) *RouterGroup {
-	return rg.HandleFunc(http.MethodGet, pattern, handler)
+// ANY registers handler for every standard HTTP method.
+func (rg *RouterGroup) ANY(pattern string, handler HandlerFunc, middlewares ...Middleware) *GroupRoute {
+	return rg.Match(anyMethods, pattern, handler, middlewares...)
 }
 
 // WithWorkerPool configures the router to use a worker pool.
@@ -149,6 +161,95 @@ func (r *Router) WithRateLimiter(maxTokens int, refillInterval time.Duration) *R
 	return r
 }
 
+// WithAutoHEAD configures whether HEAD requests to a path without a HEAD route are
+// served by its GET route with the response body discarded. Enabled by default.
+func (r *Router) WithAutoHEAD(enabled bool) *Router {
+	r.autoHEAD = enabled
+	return r
+}
+
+// WithAutoOPTIONS configures whether OPTIONS requests to a path without an OPTIONS route
+// are answered with 204 No Content and the path's Allow header. Enabled by default.
+// The response still passes through the global middleware, so CORS middleware can
+// handle preflight requests.
+func (r *Router) WithAutoOPTIONS(enabled bool) *Router {
+	r.autoOPTIONS = enabled
+	return r
+}
+
+// WithRedirectTrailingSlash configures whether a request that misses a route only because
+// of a trailing slash is redirected to the path with the slash added or removed.
+// Enabled by default.
+func (r *Router) WithRedirectTrailingSlash(enabled bool) *Router {
+	r.redirectTrailingSlash = enabled
+	return r
+}
+
+// WithRedirectFixedPath configures whether a request that misses a route is redirected
+// to the cleaned path, with duplicate slashes and dot segments removed and letter case
+// corrected, if a route matches it. Disabled by default.
+func (r *Router) WithRedirectFixedPath(enabled bool) *Router {
+	r.redirectFixedPath = enabled
+	return r
+}
+
+// WithUseRawPath configures whether routes are matched against the escaped path
+// (URL.RawPath) when it is available, so that an encoded slash such as %2F stays
+// inside a single parameter. Disabled by default.
+func (r *Router) WithUseRawPath(enabled bool) *Router {
+	r.useRawPath = enabled
+	return r
+}
+
+// WithUnescapePathValues configures whether parameter values matched against the raw
+// path are unescaped before they reach the handler. It only applies together with
+// WithUseRawPath. Enabled by default.
+func (r *Router) WithUnescapePathValues(enabled bool) *Router {
+	r.unescapePathValues = enabled
+	return r
+}
+
+// WithRequestLogging configures whether every request is logged with its duration.
+// Enabled by default; errors returned by handlers are logged either way.
+func (r *Router) WithRequestLogging(enabled bool) *Router {
+	r.requestLogging = enabled
+	return r
+}
+
+// WithMaxBodySize limits request bodies to n bytes; 0, the default, leaves them unlimited.
+// Reading past the limit fails, and the error is rendered as 413 Request Entity Too Large.
+// Groups and routes can replace the limit with their own WithMaxBodySize and MaxBodySize.
+func (r *Router) WithMaxBodySize(n int64) *Router {
+	r.maxBodySize = n
+	return r
+}
+
+// WithMultipartMemory sets how many bytes of a multipart form are kept in memory
+// when it is parsed, 32 MB by default. The remaining file parts are stored in
+// temporary files. The size of the whole form is bounded by WithMaxBodySize.
+func (r *Router) WithMultipartMemory(n int64) *Router {
+	r.multipartMemory = n
+	return r
+}
+
+// WithStrictRoutes configures how route registration errors are reported.
+// In strict mode, the default, registering a malformed, duplicate or ambiguous
+// route panics. Otherwise the error is logged and returned by Err and Start.
+func (r *Router) WithStrictRoutes(strict bool) *Router {
+	r.strict = strict
+	return r
+}
+
+// WithRoutesEndpoint registers a GET endpoint at path, e.g. "/_routes", that renders
+// Routes as JSON. It is meant for internal tooling; protect it with middleware or
+// host routing if the router is publicly reachable.
+func (r *Router) WithRoutesEndpoint(path string) *Router {
+	r.GET(path, func(c *Context) error {
+		return c.JSON(http.StatusOK, r.Routes())
+	})
+	return r
+}
+
 // WithFileLogging configures the router to log to the specified file in addition to the console.
 // If the file cannot be opened, it logs an error and leaves the existing logger intact.
 func (r *Router) WithFileLogging(filePath string) *Router {
@@ -163,152 +264,699 @@ func (r *Router) WithFileLogging(filePath string) *Router {
 }
 
 // Handle registers a new route.
-func (r *Router) Handle(method, pattern string, handler http.HandlerFunc) *Router {
-	rt := route{
-		method:  method,
-		pattern: pattern,
-		handler: handler,
+func (r *Router) Handle(method, pattern string, handler http.HandlerFunc, middlewares ...Middleware) *Route {
+	routes := newRoutes(nil, []string{method}, pattern, httpHandler(handler), middlewares)
+	routes[0].handlerName = getFunctionName(handler)
+	return &Route{r, r.addRoutes(routes)}
+}
+
+// addRoutes stores the routes of a registration call in the route table.
+// Routes sharing a pattern are kept side by side in a methodRoutes map.
+// Malformed, duplicate and ambiguous routes panic in strict mode; otherwise
+// the error is logged and reported by Err. Either way, none of the routes is added.
+// It returns the routes added, or nil if they were not.
+func (r *Router) addRoutes(routes []route) []route {
+	site := callerSite()
+	for i := range routes {
+		routes[i].site = site
+	}
+	ok := r.update(func(t *routeTable) error {
+		for _, rt := range routes {
+			if err := t.insertRoute(rt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if !ok {
+		return nil
+	}
+	return routes
+}
+
+// update applies a registration change to a copy of the route table and publishes
+// the copy, so that requests in flight keep reading the previous table.
+// If fn fails, the table is left unchanged, the error goes to registrationError and
+// update returns false.
+func (r *Router) update(fn func(t *routeTable) error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	table := r.table.Load().clone()
+	if err := fn(table); err != nil {
+		r.registrationError(err)
+		return false
+	}
+	r.table.Store(table)
+	return true
+}
+
+// registrationError panics with err in strict mode and records it otherwise.
+// It must be called with r.mu held.
+func (r *Router) registrationError(err error) {
+	if r.strict {
+		panic(err)
 	}
-	if !strings.ContainsAny(pattern, ":*") {
-		r.staticRoutes.Insert(pattern, rt)
+	golog.Error("{}", err.Error())
+	r.errs = append(r.errs, err)
+}
+
+// insertRoute stores a route, returning an error naming both registration
+// sites if it duplicates or conflicts with an existing route.
+func (t *routeTable) insertRoute(rt route) error {
+	routes := t.routesFor(rt.host).routes
+	if val, found := routes.GetRoute(rt.pattern); found {
+		mr := val.(methodRoutes)
+		if existing, ok := mr[rt.method]; ok {
+			return fmt.Errorf("draupnir: route %s %s registered at %s duplicates the route registered at %s", rt.method, rt.pattern, rt.site, existing.site)
+		}
+		mr = maps.Clone(mr)
+		mr[rt.method] = rt
+		return routes.InsertRoute(rt.pattern, mr)
+	}
+
+	err := routes.InsertRoute(rt.pattern, methodRoutes{rt.method: rt})
+	var conflict *tree.ConflictError
+	if errors.As(err, &conflict) {
+		mr := conflict.Value.(methodRoutes)
+		existing := mr[mr.methods()[0]]
+		return fmt.Errorf("draupnir: route %s %s registered at %s conflicts with %s %s registered at %s", rt.method, rt.pattern, rt.site, existing.method, existing.pattern, existing.site)
+	}
+	if err != nil {
+		return fmt.Errorf("draupnir: invalid route %s %s registered at %s: %w", rt.method, rt.pattern, rt.site, err)
+	}
+	return nil
+}
+
+// Remove unregisters the route for method and pattern and reports whether it existed.
+// Like registration, it is safe while the server is running: requests already being
+// routed finish against the previous route table.
+func (r *Router) Remove(method, pattern string) bool {
+	return r.remove("", method, pattern)
+}
+
+// remove unregisters a route of the given host.
+func (r *Router) remove(host, method, pattern string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	table := r.table.Load().clone()
+	routes := table.routesFor(host).routes
+	val, found := routes.GetRoute(pattern)
+	if !found {
+		return false
+	}
+	mr := maps.Clone(val.(methodRoutes))
+	rt, ok := mr[method]
+	if !ok {
+		return false
+	}
+
+	delete(mr, method)
+	if len(mr) == 0 {
+		routes.DeleteRoute(pattern)
 	} else {
-		r.dynamicRoutes = append(r.dynamicRoutes, rt)
+		routes.InsertRoute(pattern, mr)
 	}
-	return r
+	if rt.name != "" && !mr.named(rt.name) {
+		table.names.Delete(rt.name)
+	}
+
+	r.table.Store(table)
+	return true
+}
+
+// Err returns the errors of every route that failed to register in lenient mode.
+// Start refuses to start the server while Err is non-nil.
+func (r *Router) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(r.errs...)
 }
 
 // HandleFunc registers a route using a Context-based handler.
-func (r *Router) HandleFunc(method, pattern string, handler func(*Context)) *Router {
+// The route middlewares run after the global middlewares, in the order given:
+//
+//	router.GET("/admin", adminHandler, authMiddleware, auditMiddleware)
+func (r *Router) HandleFunc(method, pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.Match([]string{method}, pattern, handler, middlewares...)
+}
+
+// Match registers handler for each of methods. The routes are registered together,
+// so Name and Meta apply to all of them:
+//
+//	router.Match([]string{"GET", "POST"}, "/search", search).Name("search")
+func (r *Router) Match(methods []string, pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return &Route{r, r.addRoutes(newRoutes(nil, methods, pattern, handler, middlewares))}
+}
+
+// anyMethods are the methods registered by ANY.
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch,
+	http.MethodOptions, http.MethodHead, http.MethodTrace, http.MethodConnect,
+}
+
+// newRoutes builds the routes registering handler for each of methods,
+// under the host and prefix of rg if it is not nil.
+func newRoutes(rg *RouterGroup, methods []string, pattern string, handler HandlerFunc, middlewares []Middleware) []route {
+	var entries []middlewareEntry
+	for _, m := range middlewares {
+		entries = append(entries, newMiddlewareEntry(m))
+	}
+
 	rt := route{
-		method:  method,
-		pattern: pattern,
-		handler: func(w http.ResponseWriter, req *http.Request) {
-			ctx := &Context{Writer: w, Request: req}
-			handler(ctx)
-		},
-	}
-	if !strings.ContainsAny(pattern, ":*") {
-		r.staticRoutes.Insert(pattern, rt)
-	} else {
-		r.dynamicRoutes = append(r.dynamicRoutes, rt)
+		pattern:     pattern,
+		handler:     handler,
+		handlerName: getFunctionName(handler),
+		middlewares: entries,
+	}
+	if rg != nil {
+		rt.host = rg.host
+		rt.pattern = rg.prefix + pattern
+		rt.group = rg
+	}
+
+	routes := make([]route, len(methods))
+	for i, method := range methods {
+		routes[i] = rt
+		routes[i].method = method
+	}
+	return routes
+}
+
+func (r *Router) GET(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc("GET", pattern, handler, middlewares...)
+}
+
+func (r *Router) POST(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc(http.MethodPost, pattern, handler, middlewares...)
+}
+
+func (r *Router) PUT(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc(http.MethodPut, pattern, handler, middlewares...)
+}
+
+func (r *Router) DELETE(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc(http.MethodDelete, pattern, handler, middlewares...)
+}
+
+func (r *Router) PATCH(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc(http.MethodPatch, pattern, handler, middlewares...)
+}
+
+func (r *Router) OPTIONS(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc(http.MethodOptions, pattern, handler, middlewares...)
+}
+
+func (r *Router) HEAD(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc(http.MethodHead, pattern, handler, middlewares...)
+}
+
+func (r *Router) TRACE(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc(http.MethodTrace, pattern, handler, middlewares...)
+}
+
+func (r *Router) CONNECT(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.HandleFunc(http.MethodConnect, pattern, handler, middlewares...)
+}
+
+// ANY registers handler for every standard HTTP method.
+func (r *Router) ANY(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.Match(anyMethods, pattern, handler, middlewares...)
+}
+
+// NotFound sets the handler for requests that match no route, replacing http.NotFound.
+// Groups may override it for their prefix. The handler runs through the global middleware.
+func (r *Router) NotFound(handler HandlerFunc) *Router {
+	r.setFallback("", "", false, route{handler: handler})
+	return r
+}
+
+// MethodNotAllowed sets the handler for requests whose path matches a route but not its
+// method, replacing the plain-text 405 response. The Allow header is set before it runs.
+// Groups may override it for their prefix. The handler runs through the global middleware.
+func (r *Router) MethodNotAllowed(handler HandlerFunc) *Router {
+	r.setFallback("", "", true, route{handler: handler})
+	return r
+}
+
+// setFallback stores a NotFound or MethodNotAllowed handler for a group prefix and its subtree.
+func (r *Router) setFallback(host, prefix string, methodNotAllowed bool, rt route) {
+	site := callerSite()
+	r.update(func(t *routeTable) error {
+		h := t.routesFor(host)
+		handlers := h.notFound
+		if methodNotAllowed {
+			handlers = h.methodNotAllowed
+		}
+		for _, pattern := range subtreePatterns(prefix) {
+			if err := handlers.InsertRoute(pattern, rt); err != nil {
+				return fmt.Errorf("draupnir: invalid group prefix %q registered at %s: %w", prefix, site, err)
+			}
+		}
+		return nil
+	})
+}
+
+// httpHandler adapts an http.HandlerFunc to HandlerFunc.
+func httpHandler(handler http.HandlerFunc) HandlerFunc {
+	return func(c *Context) error {
+		handler(c.Writer, c.Request)
+		return nil
 	}
+}
+
+// Route is returned by the methods registering routes on a Router. Its Name, Meta and
+// MaxBodySize methods apply to the routes of that registration, even while other
+// goroutines register routes; the methods of the Router remain available for chaining:
+//
+//	router.GET("/users/:id", showUser).Name("user.show").GET("/users", listUsers)
+type Route struct {
+	*Router
+	routes []route // routes of the registration, nil if it failed
+}
+
+// GroupRoute is returned by the methods registering routes on a RouterGroup.
+// See Route.
+type GroupRoute struct {
+	*RouterGroup
+	routes []route // routes of the registration, nil if it failed
+}
+
+// Name assigns a name to the routes, so that URL can build paths to them.
+// Naming routes twice or reusing a name is a registration error.
+func (r *Route) Name(name string) *Route {
+	r.Router.name(r.routes, name, callerSite())
 	return r
 }
 
-func (r *Router) GET(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc("GET", pattern, handler)
+// Meta attaches a user metadata entry to the routes.
+// Metadata is reported by Routes and does not affect routing.
+func (r *Route) Meta(key string, value any) *Route {
+	r.Router.meta(r.routes, key, value)
+	return r
 }
 
-func (r *Router) POST(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodPost, pattern, handler)
+// MaxBodySize limits the request body of the routes to n bytes, replacing the limit
+// of their group or of the router. A negative n removes the limit.
+func (r *Route) MaxBodySize(n int64) *Route {
+	r.Router.maxBody(r.routes, n)
+	return r
 }
 
-func (r *Router) PUT(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodPut, pattern, handler)
+// Name assigns a name to the routes. See Route.Name.
+func (r *GroupRoute) Name(name string) *GroupRoute {
+	r.router.name(r.routes, name, callerSite())
+	return r
 }
 
-func (r *Router) DELETE(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodDelete, pattern, handler)
+// Meta attaches a user metadata entry to the routes. See Route.Meta.
+func (r *GroupRoute) Meta(key string, value any) *GroupRoute {
+	r.router.meta(r.routes, key, value)
+	return r
 }
 
-func (r *Router) PATCH(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodPatch, pattern, handler)
+// MaxBodySize limits the request body of the routes. See Route.MaxBodySize.
+func (r *GroupRoute) MaxBodySize(n int64) *GroupRoute {
+	r.router.maxBody(r.routes, n)
+	return r
 }
 
-func (r *Router) OPTIONS(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodOptions, pattern, handler)
+// name names the routes of a registration. URL builds paths from the pattern of the first.
+func (r *Router) name(routes []route, name, site string) {
+	if len(routes) == 0 {
+		// The registration failed and its error was reported
+		return
+	}
+	r.update(func(t *routeTable) error {
+		if name == "" {
+			return fmt.Errorf("draupnir: empty route name registered at %s", site)
+		}
+		if existing, ok := t.names.Get(name); ok {
+			return fmt.Errorf("draupnir: route name %q registered at %s is already used by %s", name, site, existing)
+		}
+		err := t.updateRoutes(routes, func(rt *route) error {
+			if rt.name != "" {
+				return fmt.Errorf("draupnir: route %s %s registered at %s is already named %q", rt.method, rt.pattern, rt.site, rt.name)
+			}
+			rt.name = name
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		pattern := routes[0].pattern
+		if routes[0].mount != "" {
+			pattern = routes[0].mount
+		}
+		t.names.Insert(name, pattern)
+		return nil
+	})
 }
 
-func (r *Router) HEAD(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodHead, pattern, handler)
+// meta attaches a metadata entry to the routes of a registration.
+func (r *Router) meta(routes []route, key string, value any) {
+	if len(routes) == 0 {
+		return
+	}
+	r.update(func(t *routeTable) error {
+		return t.updateRoutes(routes, func(rt *route) error {
+			meta := make(map[string]any, len(rt.meta)+1)
+			for k, v := range rt.meta {
+				meta[k] = v
+			}
+			meta[key] = value
+			rt.meta = meta
+			return nil
+		})
+	})
 }
 
-func (r *Router) TRACE(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodTrace, pattern, handler)
+// maxBody sets the body size limit of the routes of a registration.
+func (r *Router) maxBody(routes []route, n int64) {
+	if len(routes) == 0 {
+		return
+	}
+	r.update(func(t *routeTable) error {
+		return t.updateRoutes(routes, func(rt *route) error {
+			rt.maxBodySize = n
+			return nil
+		})
+	})
 }
 
-func (r *Router) CONNECT(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodConnect, pattern, handler)
+// updateRoutes applies fn to the current version of each of routes and stores the
+// result in t. It fails if one of the routes was removed.
+func (t *routeTable) updateRoutes(routes []route, fn func(*route) error) error {
+	for _, registered := range routes {
+		tree := t.routesFor(registered.host).routes
+		val, found := tree.GetRoute(registered.pattern)
+		var rt route
+		if found {
+			rt, found = val.(methodRoutes)[registered.method]
+		}
+		if !found {
+			return fmt.Errorf("draupnir: route %s %s registered at %s was removed", registered.method, registered.pattern, registered.site)
+		}
+		if err := fn(&rt); err != nil {
+			return err
+		}
+		mr := maps.Clone(val.(methodRoutes))
+		mr[rt.method] = rt
+		tree.InsertRoute(rt.pattern, mr)
+	}
+	return nil
 }
 
-func (r *Router) ANY(pattern string, handler func(*Context)) *Router {
-	return r.HandleFunc(http.MethodGet, pattern, handler)
+// URL builds the path of the route registered under name.
+// Parameters are given as alternating name and value pairs and are escaped;
+// catch-all values keep their slashes:
+//
+//	router.GET("/users/:id", showUser).Name("user.show")
+//	path, err := router.URL("user.show", "id", "42") // "/users/42"
+//
+// It returns an error if the name is unknown or a parameter is missing or
+// does not satisfy its constraint.
+func (r *Router) URL(name string, pairs ...string) (string, error) {
+	val, ok := r.table.Load().names.Get(name)
+	if !ok {
+		return "", fmt.Errorf("draupnir: no route named %q", name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("draupnir: odd number of parameters for route %q", name)
+	}
+
+	path, err := tree.Build(val.(string), func(key string) (string, bool) {
+		for i := 0; i < len(pairs); i += 2 {
+			if pairs[i] == key {
+				return pairs[i+1], true
+			}
+		}
+		return "", false
+	})
+	if err != nil {
+		return "", fmt.Errorf("draupnir: route %q: %w", name, err)
+	}
+	return path, nil
 }
 
 // ListRoutes returns a slice of strings describing all registered routes.
+// Routes registered on a host group are listed with their host before the path.
 func (r *Router) ListRoutes() []string {
 	var routes []string
-	r.staticRoutes.Walk(func(path string, v interface{}) bool {
-		rt := v.(route)
-		routes = append(routes, rt.method+" "+rt.pattern)
-		return false
-	})
-	for _, rt := range r.dynamicRoutes {
-		routes = append(routes, rt.method+" "+rt.pattern)
+	for _, info := range r.Routes() {
+		routes = append(routes, info.Method+" "+info.Host+info.Pattern)
 	}
 	return routes
 }
 
 // ServeHTTP implements http.Handler.
-// It checks if the request matches a static or dynamic route and executes the corresponding handler.
-// If no route matches, it returns a 404 Not Found error.
-// It also logs the request details and execution time.
+// It looks the request host and path up in the route trees and executes the handler registered for the request method.
+// If the path matches but no handler is registered for the request method, it sets an
+// Allow header listing every registered method and runs the MethodNotAllowed handler.
+// If no route matches, it runs the NotFound handler. Both default to plain-text errors
+// and run through the global middleware.
+// It also logs the request details and execution time unless request logging is disabled.
+// The Context of the request is recycled once ServeHTTP returns.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.serve(w, req, nil)
+}
+
+// serve routes a request. If next is not nil, requests matching no route are passed to it.
+func (r *Router) serve(w http.ResponseWriter, req *http.Request, next http.Handler) {
 	start := time.Now()
 
-	if val, found := r.staticRoutes.Get(req.URL.Path); found {
-		rt := val.(route)
-		if rt.method == req.Method || req.Method == http.MethodOptions {
-			r.executeHandler(w, req, rt.handler)
-			golog.Debug("(STATIC ROUTE) Request: {} {}, from: {} completed in {}", req.Method, req.URL.Path, req.RemoteAddr, time.Since(start))
+	c := r.pool.Get().(*Context)
+	c.reset(r, w, req)
+	defer r.pool.Put(c)
+
+	path := req.URL.Path
+	unescape := false
+	if r.useRawPath && req.URL.RawPath != "" {
+		path = req.URL.RawPath
+		unescape = r.unescapePathValues
+	}
+
+	table := r.table.Load()
+	mr, params, found := table.match(req, path, c.params)
+	if !found && next != nil {
+		next.ServeHTTP(w, req)
+		return
+	}
+	if !found {
+		if target, ok := r.redirectPath(table, req, path); ok {
+			r.redirect(w, req, target)
+			if r.requestLogging {
+				golog.Debug("Redirected {} {} to {}", req.Method, req.URL.Path, target)
+			}
 			return
 		}
+		r.executeHandler(c, table.fallback(req, path, false, notFoundRoute))
+		if r.requestLogging {
+			golog.Warn("Route not found {}", time.Since(start).String())
+		}
+		return
+	}
 
-		w.Header().Set("Allow", rt.method)
-		http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
-		golog.Warn("Method not allowed (static) {}", time.Since(start).String())
+	rt, ok := r.methodRoute(c, mr, req.Method)
+	if !ok {
+		w.Header().Set("Allow", r.allow(mr))
+		r.executeHandler(c, table.fallback(req, path, true, methodNotAllowedRoute))
+		if r.requestLogging {
+			golog.Warn("Method not allowed {}", time.Since(start).String())
+		}
 		return
 	}
 
-	for _, rt := range r.dynamicRoutes {
-		if params, ok := matchPattern(rt.pattern, req.URL.Path); ok && (rt.method == req.Method || req.Method == http.MethodOptions) {
-			ctx := context.WithValue(req.Context(), paramsKey, params)
-			r.executeHandler(w, req.WithContext(ctx), rt.handler)
-			golog.Debug("(DYNAMIC ROUTE) Request: {} {}, from: {} completed in {}", req.Method, req.URL.Path, req.RemoteAddr, time.Since(start))
-			return
+	if unescape {
+		for i := range params {
+			if v, err := url.PathUnescape(params[i].Value); err == nil {
+				params[i].Value = v
+			}
+		}
+	}
+	c.params = params
+	r.executeHandler(c, rt)
+	if r.requestLogging {
+		golog.Debug("Request: {} {}, from: {} completed in {}", req.Method, req.URL.Path, req.RemoteAddr, time.Since(start))
+	}
+}
+
+// notFoundRoute and methodNotAllowedRoute hold the default NotFound and MethodNotAllowed handlers.
+var (
+	notFoundRoute = route{handler: httpHandler(http.NotFound)}
+
+	methodNotAllowedRoute = route{handler: func(c *Context) error {
+		http.Error(c.Writer, "405 method not allowed", http.StatusMethodNotAllowed)
+		return nil
+	}}
+)
+
+// redirectPath returns the canonical path of a request that missed a route only
+// because of a trailing slash, duplicate slashes, dot segments or letter case.
+func (r *Router) redirectPath(table *routeTable, req *http.Request, path string) (string, bool) {
+	if req.Method == http.MethodConnect || path == "/" {
+		return "", false
+	}
+
+	if r.redirectTrailingSlash {
+		if _, _, found := table.match(req, toggleTrailingSlash(path), nil); found {
+			return toggleTrailingSlash(path), true
+		}
+	}
+
+	if r.redirectFixedPath {
+		fixed := cleanPath(path)
+		if fixed, ok := table.matchFold(req, fixed); ok {
+			return fixed, true
+		}
+		if r.redirectTrailingSlash && fixed != "/" {
+			if fixed, ok := table.matchFold(req, toggleTrailingSlash(fixed)); ok {
+				return fixed, true
+			}
+		}
+	}
+	return "", false
+}
+
+// redirect sends the client to target, keeping the query string.
+// GET requests get 301 Moved Permanently, other methods 308 Permanent Redirect
+// so that the method and body are preserved.
+func (r *Router) redirect(w http.ResponseWriter, req *http.Request, target string) {
+	if !r.useRawPath || req.URL.RawPath == "" {
+		target = (&url.URL{Path: target}).EscapedPath()
+	}
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+
+	code := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet {
+		code = http.StatusMovedPermanently
+	}
+	http.Redirect(w, req, target, code)
+}
+
+// toggleTrailingSlash adds a trailing slash to path or removes the one it has.
+func toggleTrailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path[:len(path)-1]
+	}
+	return path + "/"
+}
+
+// cleanPath removes duplicate slashes and dot segments from path, keeping a trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := pathpkg.Clean(p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// methodRoute picks the route serving method on a path: the route registered for
+// method, then a route accepting any method, then the automatic HEAD and OPTIONS
+// responses when enabled. It reports false if the method is not allowed.
+func (r *Router) methodRoute(c *Context, mr methodRoutes, method string) (route, bool) {
+	if rt, ok := mr[method]; ok {
+		return rt, true
+	}
+	if rt, ok := mr[methodAny]; ok {
+		return rt, true
+	}
+
+	switch {
+	case method == http.MethodHead && r.autoHEAD:
+		if rt, ok := mr[http.MethodGet]; ok {
+			c.writer.ResponseWriter = headResponseWriter{c.writer.ResponseWriter}
+			return rt, true
 		}
+	case method == http.MethodOptions && r.autoOPTIONS:
+		allow := r.allow(mr)
+		return route{handler: func(c *Context) error {
+			c.Writer.Header().Set("Allow", allow)
+			c.Writer.WriteHeader(http.StatusNoContent)
+			return nil
+		}}, true
 	}
+	return route{}, false
+}
+
+// allow returns the Allow header value for a path: every registered method plus
+// the methods answered automatically.
+func (r *Router) allow(mr methodRoutes) string {
+	methods := mr.methods()
+	if _, ok := mr[http.MethodGet]; ok && r.autoHEAD && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if r.autoOPTIONS && !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	slices.Sort(methods)
+	return strings.Join(methods, ", ")
+}
 
-	http.NotFound(w, req)
-	golog.Warn("Route not found {}", time.Since(start).String())
+// headResponseWriter discards the body written by a GET handler serving a HEAD request.
+type headResponseWriter struct {
+	http.ResponseWriter
 }
 
-// executeHandler runs the handler with the middleware chain and rate limiter.
-func (r *Router) executeHandler(w http.ResponseWriter, req *http.Request, handler http.HandlerFunc) {
-	finalHandler := handler
-	for i := len(r.middlewares) - 1; i >= 0; i-- {
-		finalHandler = r.middlewares[i](finalHandler)
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// named reports whether a route of mr is named name.
+func (mr methodRoutes) named(name string) bool {
+	for _, rt := range mr {
+		if rt.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// methods returns the registered methods in sorted order.
+func (mr methodRoutes) methods() []string {
+	methods := make([]string, 0, len(mr))
+	for method := range mr {
+		methods = append(methods, method)
 	}
+	slices.Sort(methods)
+	return methods
+}
 
+// executeHandler runs a route for the request of c with the middleware chain and rate limiter.
+func (r *Router) executeHandler(c *Context, rt route) {
 	if r.rateLimiter != nil && !r.rateLimiter.Allow() {
-		http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+		http.Error(c.Writer, "429 Too Many Requests", http.StatusTooManyRequests)
 		return
 	}
+	if r.workerPool == nil {
+		r.handle(c, rt)
+		return
+	}
+	r.executeOnPool(c, rt)
+}
 
-	if r.workerPool != nil {
-		done := make(chan struct{})
-		err := r.workerPool.Submit(func() {
-			finalHandler(w, req)
-			close(done)
-		})
-		if err != nil {
-			http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
-			return
-		}
-		<-done // wait for completion
-	} else {
-		finalHandler(w, req)
+// executeOnPool runs a route on the worker pool and waits for it to complete.
+// It is kept apart from executeHandler so that the common path does not allocate closures.
+func (r *Router) executeOnPool(c *Context, rt route) {
+	done := make(chan struct{})
+	err := r.workerPool.Submit(func() {
+		r.handle(c, rt)
+		close(done)
+	})
+	if err != nil {
+		http.Error(c.Writer, "503 Service Unavailable", http.StatusServiceUnavailable)
+		return
 	}
+	<-done // wait for completion
 }
 
 // Start launches the HTTP server on the specified port after printing full configuration.
@@ -329,6 +977,9 @@ func (r *Router) executeHandler(w http.ResponseWriter, req *http.Request, handle
 //
 //	Ensure to handle graceful shutdowns and cleanup as needed.
 func (r *Router) Start(port string) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
 	r.printStartupInfo()
 	r.printConfiguration()
 	golog.Info("Starting server in port {}", port)
@@ -378,11 +1029,11 @@ func (r *Router) printConfiguration() {
 		golog.Info("Worker Pool not configured")
 	}
 
-	if len(r.middlewares) > 0 {
+	if middlewares := r.middlewares.load(); len(middlewares) > 0 {
 		golog.Info("-------------------------- Middleware Chain ---------------------------")
 		golog.Info("--")
-		for i, mw := range r.middlewares {
-			golog.Info("Middleware {}: {}", i, getFunctionName(mw))
+		for i, mw := range middlewares {
+			golog.Info("Middleware {}: {}", i, mw.name)
 		}
 		golog.Info("--")
 		golog.Info("-------------------------- Middleware Chain ---------------------------")
@@ -390,28 +1041,21 @@ func (r *Router) printConfiguration() {
 
 }
 
-// matchPattern compares a route pattern with a request path.
-func matchPattern(pattern, path string) (map[string]string, bool) {
-	patternParts := splitPath(pattern)
-	pathParts := splitPath(path)
-	if len(patternParts) != len(pathParts) {
-		return nil, false
-	}
-	params := make(map[string]string)
-	for i, part := range patternParts {
-		if len(part) > 0 && part[0] == ':' {
-			key := part[1:]
-			params[key] = pathParts[i]
-		} else if part != pathParts[i] {
-			return nil, false
+// callerSite returns the file and line of the first caller outside this package,
+// used to report where a route was registered.
+func callerSite() string {
+	pkg := reflect.TypeOf(Router{}).PkgPath() + "."
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkg) || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return "unknown"
 		}
 	}
-	return params, true
-}
-
-// splitPath splits a URL path into non-empty segments.
-func splitPath(path string) []string {
-	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
 }
 
 // getFunctionName returns the name of a function (used for middleware identification).
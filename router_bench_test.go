@@ -0,0 +1,88 @@
+package draupnir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// discardWriter is a ResponseWriter that allocates nothing, so that the
+// benchmarks measure the router alone.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func benchmarkRoute(b *testing.B, r *Router, method, path string) {
+	req := httptest.NewRequest(method, path, nil)
+	w := &discardWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func benchmarkRouter() *Router {
+	r := New().WithRequestLogging(false)
+	noop := func(c *Context) error { return nil }
+	r.GET("/", noop)
+	r.GET("/about", noop)
+	r.GET("/users", noop)
+	r.POST("/users", noop)
+	r.GET("/users/:id<int>", noop)
+	r.GET("/users/:id/posts/:post", func(c *Context) error {
+		_ = c.Param("post")
+		return nil
+	})
+	r.GET("/files/*filepath", noop)
+	return r
+}
+
+func BenchmarkStaticRoute(b *testing.B) {
+	benchmarkRoute(b, benchmarkRouter(), http.MethodGet, "/users")
+}
+
+func BenchmarkParamRoute(b *testing.B) {
+	benchmarkRoute(b, benchmarkRouter(), http.MethodGet, "/users/42/posts/7")
+}
+
+func BenchmarkConstrainedParamRoute(b *testing.B) {
+	benchmarkRoute(b, benchmarkRouter(), http.MethodGet, "/users/42")
+}
+
+func BenchmarkCatchAllRoute(b *testing.B) {
+	benchmarkRoute(b, benchmarkRouter(), http.MethodGet, "/files/css/site.css")
+}
+
+func BenchmarkContextMiddleware(b *testing.B) {
+	r := benchmarkRouter()
+	r.Use(func(c *Context) error {
+		return c.Next()
+	})
+	benchmarkRoute(b, r, http.MethodGet, "/users/42/posts/7")
+}
+
+func BenchmarkContextStore(b *testing.B) {
+	r := benchmarkRouter()
+	r.Use(func(c *Context) error {
+		c.Set("user", "gopher")
+		return c.Next()
+	})
+	benchmarkRoute(b, r, http.MethodGet, "/users/42/posts/7")
+}
+
+func BenchmarkRegister5000Routes(b *testing.B) {
+	noop := func(c *Context) error { return nil }
+	for i := 0; i < b.N; i++ {
+		r := New().WithRequestLogging(false)
+		for n := 0; n < 5000; n++ {
+			r.GET("/resources/"+strconv.Itoa(n)+"/items/:id", noop).Name("item." + strconv.Itoa(n))
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package draupnir
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kashari/golog"
+)
+
+// TestMain sends the log of registration and handler errors to a temporary file.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "draupnir-test")
+	if err != nil {
+		panic(err)
+	}
+	if err := golog.Init(filepath.Join(dir, "test.log")); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	golog.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestRegisterWhileServing(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	r.GET("/users/:id", func(c *Context) error { return c.String(http.StatusOK, c.Param("id")) })
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if w := serve(r, http.MethodGet, "/users/42"); w.Code != http.StatusOK || w.Body.String() != "42" {
+					t.Errorf("GET /users/42 = %d %q, want 200 \"42\"", w.Code, w.Body.String())
+					return
+				}
+				serve(r, http.MethodGet, "/items/7/parts")
+			}
+		}()
+	}
+
+	for i := range 200 {
+		pattern := "/items/" + strconv.Itoa(i) + "/parts"
+		r.GET(pattern, func(c *Context) error { return nil }).Name("parts." + strconv.Itoa(i))
+		if i%2 == 0 && !r.Remove(http.MethodGet, pattern) {
+			t.Errorf("Remove(GET %s) = false, want true", pattern)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, http.MethodGet, "/items/7/parts"); w.Code != http.StatusOK {
+		t.Errorf("GET /items/7/parts = %d, want 200", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/items/8/parts"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /items/8/parts = %d, want 404", w.Code)
+	}
+	if _, err := r.URL("parts.8"); err == nil {
+		t.Error("URL(parts.8) succeeded after its route was removed")
+	}
+}
+
+func TestConcurrentNamedRegistration(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	api := r.Group("/api")
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				id := strconv.Itoa(g) + "." + strconv.Itoa(i)
+				r.GET("/r/"+id, func(c *Context) error { return nil }).Name("r."+id).Meta("id", id).MaxBodySize(int64(i))
+				api.GET("/g/"+id, func(c *Context) error { return nil }).Name("g."+id).Meta("id", id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range r.Routes() {
+		id, _ := info.Meta["id"].(string)
+		want := "/r/" + id
+		if strings.HasPrefix(info.Name, "g.") {
+			want = "/api/g/" + id
+		}
+		if info.Name[2:] != id || info.Pattern != want {
+			t.Errorf("route %s named %q has meta id %q", info.Pattern, info.Name, id)
+		}
+		if url, err := r.URL(info.Name); err != nil || url != info.Pattern {
+			t.Errorf("URL(%q) = %q, %v, want %q", info.Name, url, err, info.Pattern)
+		}
+	}
+}
+
+func TestUseWhileServing(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	api := r.Group("/api")
+	api.GET("/ping", func(c *Context) error { return c.String(http.StatusOK, "pong") })
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if w := serve(r, http.MethodGet, "/api/ping"); w.Code != http.StatusOK {
+					t.Errorf("GET /api/ping = %d, want 200", w.Code)
+					return
+				}
+				r.Routes()
+			}
+		}()
+	}
+
+	noop := func(c *Context) error { return c.Next() }
+	for range 100 {
+		r.Use(noop)
+		api.Use(noop)
+	}
+	close(stop)
+	wg.Wait()
+
+	for _, info := range r.Routes() {
+		if len(info.Middlewares) != 200 {
+			t.Errorf("route %s has %d middleware, want 200", info.Pattern, len(info.Middlewares))
+		}
+	}
+}
+
+func TestParamValue(t *testing.T) {
+	var got []any
+	r := New().WithRequestLogging(false)
+	r.GET("/n/:i<int>/:u<uint>/:f<float>/:id<uuid>/:s", func(c *Context) error {
+		got = []any{c.ParamValue("i"), c.ParamValue("u"), c.ParamValue("f"), c.ParamValue("id"), c.ParamValue("s"), c.ParamValue("missing")}
+		return nil
+	})
+
+	serve(r, http.MethodGet, "/n/-3/4/2.5/123e4567-e89b-12d3-a456-426614174000/x")
+
+	want := []any{int64(-3), uint64(4), 2.5, "123e4567-e89b-12d3-a456-426614174000", "x", nil}
+	if fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", want) {
+		t.Errorf("ParamValue = %#v, want %#v", got, want)
+	}
+}
+
+func TestConstraintDispatch(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	r.GET("/users/:id<int>", func(c *Context) error { return c.String(http.StatusOK, "id") })
+	r.GET("/users/:name<[a-z]+>", func(c *Context) error { return c.String(http.StatusOK, "name") })
+
+	for target, want := range map[string]string{"/users/42": "id", "/users/bob": "name"} {
+		if w := serve(r, http.MethodGet, target); w.Body.String() != want {
+			t.Errorf("GET %s = %q, want %q", target, w.Body.String(), want)
+		}
+	}
+	if w := serve(r, http.MethodGet, "/users/Bob"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /users/Bob = %d, want 404", w.Code)
+	}
+}
+
+func TestInvalidConstraint(t *testing.T) {
+	r := New().WithRequestLogging(false).WithStrictRoutes(false)
+	r.GET("/posts/:slug<(>", func(c *Context) error { return nil })
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "invalid constraint") {
+		t.Errorf("Err() = %v, want an invalid constraint error", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering an invalid constraint in strict mode did not panic")
+		}
+	}()
+	New().GET("/posts/:slug<(>", func(c *Context) error { return nil })
+}
+
+func TestOverlappingConstraintConflict(t *testing.T) {
+	noop := func(c *Context) error { return nil }
+	register := func(r *Router) (first, second string) {
+		_, file, line, _ := runtime.Caller(0)
+		r.GET("/u/:id<int>", noop)
+		r.GET("/u/:id<uint>", noop)
+		return fmt.Sprintf("%s:%d", file, line+1), fmt.Sprintf("%s:%d", file, line+2)
+	}
+
+	r := New().WithRequestLogging(false).WithStrictRoutes(false)
+	first, second := register(r)
+	err := r.Err()
+	if err == nil {
+		t.Fatal("Err() = nil, want a conflict")
+	}
+	if msg := err.Error(); !strings.Contains(msg, first) || !strings.Contains(msg, second) {
+		t.Errorf("Err() = %q, want both %s and %s", msg, first, second)
+	}
+	if w := serve(r, http.MethodGet, "/u/7"); w.Code != http.StatusOK {
+		t.Errorf("GET /u/7 = %d, want the first route to stay registered", w.Code)
+	}
+
+	defer func() {
+		if v := recover(); v == nil || !strings.Contains(fmt.Sprint(v), "conflicts with") {
+			t.Errorf("strict mode recovered %v, want a conflict panic", v)
+		}
+	}()
+	register(New().WithRequestLogging(false))
+}
+
+func TestRedirect(t *testing.T) {
+	noop := func(c *Context) error { return nil }
+	r := New().WithRequestLogging(false).WithRedirectFixedPath(true)
+	r.GET("/users", noop)
+	r.POST("/users", noop)
+	r.GET("/docs/", noop)
+	r.GET("/Admin/Panel", noop)
+	r.GET("/files/*path", noop)
+
+	tests := []struct {
+		method, target string
+		code           int
+		location       string
+	}{
+		{http.MethodGet, "/users/", http.StatusMovedPermanently, "/users"},
+		{http.MethodPost, "/users/", http.StatusPermanentRedirect, "/users"},
+		{http.MethodGet, "/docs", http.StatusMovedPermanently, "/docs/"},
+		{http.MethodGet, "/users/?page=2&q=a%20b", http.StatusMovedPermanently, "/users?page=2&q=a%20b"},
+		// Fixed paths
+		{http.MethodGet, "//users", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/docs/../users", http.StatusMovedPermanently, "/users"},
+		{http.MethodGet, "/admin/panel", http.StatusMovedPermanently, "/Admin/Panel"},
+		{http.MethodPost, "/USERS/", http.StatusPermanentRedirect, "/users"},
+		// An empty catch-all remainder needs the trailing slash
+		{http.MethodGet, "/files", http.StatusMovedPermanently, "/files/"},
+		{http.MethodGet, "/users", http.StatusOK, ""},
+		{http.MethodGet, "/nothing/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.target)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.target, w.Code, w.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+
+	r.WithRedirectTrailingSlash(false).WithRedirectFixedPath(false)
+	for _, target := range []string{"/users/", "//users", "/admin/panel"} {
+		if w := serve(r, http.MethodGet, target); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s without redirects = %d, want 404", target, w.Code)
+		}
+	}
+}
+
+func TestRawPath(t *testing.T) {
+	var got string
+	newRouter := func() *Router {
+		r := New().WithRequestLogging(false)
+		r.GET("/files/:name", func(c *Context) error {
+			got = c.Param("name")
+			return nil
+		})
+		return r
+	}
+
+	if w := serve(newRouter(), http.MethodGet, "/files/a%2Fb"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /files/a%%2Fb = %d, want 404 when matching the unescaped path", w.Code)
+	}
+
+	got = ""
+	if w := serve(newRouter().WithUseRawPath(true), http.MethodGet, "/files/a%2Fb"); w.Code != http.StatusOK || got != "a/b" {
+		t.Errorf("UseRawPath: GET /files/a%%2Fb = %d, name %q, want 200 \"a/b\"", w.Code, got)
+	}
+
+	got = ""
+	r := newRouter().WithUseRawPath(true).WithUnescapePathValues(false)
+	if w := serve(r, http.MethodGet, "/files/a%2Fb"); w.Code != http.StatusOK || got != "a%2Fb" {
+		t.Errorf("UnescapePathValues(false): GET /files/a%%2Fb = %d, name %q, want 200 \"a%%2Fb\"", w.Code, got)
+	}
+
+	// Redirects keep the escaped slash
+	if w := serve(r, http.MethodGet, "/files/a%2Fb/"); w.Header().Get("Location") != "/files/a%2Fb" {
+		t.Errorf("GET /files/a%%2Fb/ redirected to %q, want /files/a%%2Fb", w.Header().Get("Location"))
+	}
+}
+
+func TestAutoHEADAndOPTIONS(t *testing.T) {
+	newRouter := func() *Router {
+		r := New().WithRequestLogging(false)
+		r.GET("/users", func(c *Context) error {
+			c.Writer.Header().Set("X-Total", "2")
+			return c.String(http.StatusOK, "alice, bob")
+		})
+		r.POST("/users", func(c *Context) error { return nil })
+		r.PUT("/custom", func(c *Context) error { return nil })
+		r.OPTIONS("/custom", func(c *Context) error { return c.String(http.StatusOK, "custom options") })
+		return r
+	}
+
+	r := newRouter()
+	w := serve(r, http.MethodHead, "/users")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("X-Total") != "2" {
+		t.Errorf("HEAD /users = %d %q X-Total %q, want 200, no body and the GET headers", w.Code, w.Body.String(), w.Header().Get("X-Total"))
+	}
+
+	w = serve(r, http.MethodOptions, "/users")
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" || w.Body.Len() != 0 {
+		t.Errorf("OPTIONS /users = %d Allow %q, want 204 \"GET, HEAD, OPTIONS, POST\"", w.Code, w.Header().Get("Allow"))
+	}
+	if w = serve(r, http.MethodOptions, "/custom"); w.Body.String() != "custom options" {
+		t.Errorf("OPTIONS /custom = %d %q, want the registered handler", w.Code, w.Body.String())
+	}
+
+	w = serve(r, http.MethodDelete, "/users")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("DELETE /users = %d Allow %q, want 405 with Allow", w.Code, w.Header().Get("Allow"))
+	}
+
+	r = newRouter().WithAutoHEAD(false).WithAutoOPTIONS(false)
+	for _, method := range []string{http.MethodHead, http.MethodOptions} {
+		w = serve(r, method, "/users")
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+			t.Errorf("%s /users when disabled = %d Allow %q, want 405 \"GET, POST\"", method, w.Code, w.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestGroupFallbacks(t *testing.T) {
+	text := func(s string) HandlerFunc {
+		return func(c *Context) error { return c.String(http.StatusTeapot, s) }
+	}
+	noop := func(c *Context) error { return nil }
+
+	r := New().WithRequestLogging(false)
+	r.NotFound(text("router not found"))
+	r.MethodNotAllowed(text("router not allowed"))
+	r.GET("/users", noop)
+
+	api := r.Group("/api").Use(func(c *Context) error {
+		c.Writer.Header().Set("X-Group", "api")
+		return c.Next()
+	})
+	api.NotFound(text("api not found"))
+	api.GET("/items", noop)
+	v1 := api.Group("/v1")
+	v1.NotFound(text("v1 not found"))
+	v1.MethodNotAllowed(text("v1 not allowed"))
+	v1.GET("/items", noop)
+
+	tests := []struct {
+		method, target string
+		body, group    string
+	}{
+		{http.MethodGet, "/missing", "router not found", ""},
+		{http.MethodGet, "/apix", "router not found", ""},
+		{http.MethodGet, "/api", "api not found", "api"},
+		{http.MethodGet, "/api/missing", "api not found", "api"},
+		{http.MethodGet, "/api/v1/missing/deep", "v1 not found", "api"},
+		{http.MethodPost, "/users", "router not allowed", ""},
+		// Without a MethodNotAllowed handler of its own, /api uses the router's
+		{http.MethodPost, "/api/items", "router not allowed", ""},
+		{http.MethodPost, "/api/v1/items", "v1 not allowed", "api"},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.target)
+		if w.Code != http.StatusTeapot || w.Body.String() != tt.body || w.Header().Get("X-Group") != tt.group {
+			t.Errorf("%s %s = %d %q X-Group %q, want %q %q", tt.method, tt.target, w.Code, w.Body.String(), w.Header().Get("X-Group"), tt.body, tt.group)
+		}
+	}
+	if w := serve(r, http.MethodPost, "/api/v1/items"); w.Header().Get("Allow") == "" {
+		t.Error("group MethodNotAllowed handler ran without the Allow header")
+	}
+}
+
+func TestNamedRoutes(t *testing.T) {
+	noop := func(c *Context) error { return nil }
+	r := New().WithRequestLogging(false).WithStrictRoutes(false)
+	r.GET("/users/:id<int>", noop).Name("user.show")
+	r.GET("/search/:q", noop).Name("search")
+	r.GET("/files/*path", noop).Name("files")
+	r.Match([]string{http.MethodGet, http.MethodPost}, "/login", noop).Name("login")
+	r.Group("/api").GET("/items/:id", func(c *Context) error {
+		url, err := c.URLFor("user.show", "id", c.Param("id"))
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, url)
+	}).Name("api.item")
+
+	tests := []struct {
+		name  string
+		pairs []string
+		want  string
+	}{
+		{"user.show", []string{"id", "42"}, "/users/42"},
+		{"search", []string{"q", "a b/c?d"}, "/search/a%20b%2Fc%3Fd"},
+		{"files", []string{"path", "docs/a b.txt"}, "/files/docs/a%20b.txt"},
+		{"login", nil, "/login"},
+		{"api.item", []string{"id", "x"}, "/api/items/x"},
+	}
+	for _, tt := range tests {
+		if got, err := r.URL(tt.name, tt.pairs...); err != nil || got != tt.want {
+			t.Errorf("URL(%q, %q) = %q, %v, want %q", tt.name, tt.pairs, got, err, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		name  string
+		pairs []string
+	}{
+		{"user.show", nil},
+		{"user.show", []string{"id", "abc"}},
+		{"user.show", []string{"id"}},
+		{"search", []string{"q", ""}},
+		{"unknown", nil},
+	} {
+		if got, err := r.URL(tt.name, tt.pairs...); err == nil {
+			t.Errorf("URL(%q, %q) = %q, want an error", tt.name, tt.pairs, got)
+		}
+	}
+
+	if w := serve(r, http.MethodGet, "/api/items/7"); w.Body.String() != "/users/7" {
+		t.Errorf("URLFor in a handler = %q, want /users/7", w.Body.String())
+	}
+
+	if err := r.Err(); err != nil {
+		t.Fatalf("Err() = %v before reusing a name", err)
+	}
+	r.GET("/people/:id", noop).Name("user.show")
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), `route name "user.show"`) || !strings.Contains(err.Error(), "already used by /users/:id<int>") {
+		t.Errorf("Err() = %v, want the reused name reported", err)
+	}
+	if got, _ := r.URL("user.show", "id", "1"); got != "/users/1" {
+		t.Errorf("URL(user.show) = %q after the failed reuse, want /users/1", got)
+	}
+}
@@ -0,0 +1,64 @@
+package draupnir
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Routes returns a description of every registered route, sorted by host, pattern and method.
+// A mount is described once, by its prefix, with the method ANY.
+// The middleware chain lists the global, group and route middleware in the order they run.
+func (r *Router) Routes() []RouteInfo {
+	var routes []RouteInfo
+	walk := func(path string, v interface{}) bool {
+		for _, rt := range v.(methodRoutes) {
+			if rt.mount != "" && rt.pattern != subtreePatterns(rt.mount)[0] {
+				// A mount is reported once, for its prefix
+				continue
+			}
+			routes = append(routes, r.routeInfo(rt))
+		}
+		return false
+	}
+	table := r.table.Load()
+	table.root.routes.Walk(walk)
+	for _, h := range table.hosts {
+		h.routes.Walk(walk)
+	}
+
+	slices.SortFunc(routes, func(a, b RouteInfo) int {
+		return cmp.Or(cmp.Compare(a.Host, b.Host), cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Method, b.Method))
+	})
+	return routes
+}
+
+// routeInfo describes a single route.
+func (r *Router) routeInfo(rt route) RouteInfo {
+	info := RouteInfo{
+		Method:  rt.method,
+		Host:    rt.host,
+		Pattern: rt.pattern,
+		Name:    rt.name,
+		Handler: rt.handlerName,
+		Meta:    rt.meta,
+	}
+
+	if rt.mount != "" {
+		info.Method = "ANY"
+		info.Pattern = rt.mount
+	}
+
+	for _, mw := range r.middlewares.load() {
+		info.Middlewares = append(info.Middlewares, mw.name)
+	}
+	if rt.group != nil {
+		info.Group = rt.group.prefix
+		for _, mw := range rt.group.middlewares.load() {
+			info.Middlewares = append(info.Middlewares, mw.name)
+		}
+	}
+	for _, mw := range rt.middlewares {
+		info.Middlewares = append(info.Middlewares, mw.name)
+	}
+	return info
+}
@@ -0,0 +1,151 @@
+package draupnir
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func globalMiddleware(c *Context) error { return c.Next() }
+func groupMiddleware(c *Context) error  { return c.Next() }
+func routeMiddleware(c *Context) error  { return c.Next() }
+func showUser(c *Context) error         { return nil }
+
+func healthCheck(w http.ResponseWriter, req *http.Request) {}
+
+func TestRoutes(t *testing.T) {
+	r := New().WithRequestLogging(false)
+	r.Use(globalMiddleware)
+	api := r.Group("/api").Use(groupMiddleware)
+	api.GET("/users/:id", showUser, routeMiddleware).Name("user.show").Meta("scope", "users:read")
+	r.Handle(http.MethodGet, "/health", healthCheck)
+
+	const pkg = "github.com/kashari/draupnir."
+	want := []RouteInfo{
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/api/users/:id",
+			Name:        "user.show",
+			Handler:     pkg + "showUser",
+			Middlewares: []string{pkg + "globalMiddleware", pkg + "groupMiddleware", pkg + "routeMiddleware"},
+			Group:       "/api",
+			Meta:        map[string]any{"scope": "users:read"},
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/health",
+			Handler:     pkg + "healthCheck",
+			Middlewares: []string{pkg + "globalMiddleware"},
+		},
+	}
+
+	got := r.Routes()
+	if len(got) != len(want) {
+		t.Fatalf("Routes() = %+v, want %d routes", got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Method != w.Method || g.Host != w.Host || g.Pattern != w.Pattern || g.Name != w.Name ||
+			g.Handler != w.Handler || g.Group != w.Group || !slices.Equal(g.Middlewares, w.Middlewares) ||
+			len(g.Meta) != len(w.Meta) || g.Meta["scope"] != w.Meta["scope"] {
+			t.Errorf("Routes()[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+
+	// Middleware added later runs for the routes registered before, and is reported
+	api.Use(globalMiddleware)
+	if mws := r.Routes()[0].Middlewares; len(mws) != 4 || mws[2] != pkg+"globalMiddleware" {
+		t.Errorf("middleware chain = %q after Use, want the group middleware before the route's", mws)
+	}
+}
+
+func TestRoutesEndpoint(t *testing.T) {
+	r := New().WithRequestLogging(false).WithRoutesEndpoint("/_routes")
+	r.Host("api.example.com").POST("/items", showUser).Meta("public", true)
+
+	w := serve(r, http.MethodGet, "/_routes")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /_routes = %d, want 200", w.Code)
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GET /_routes = %s, want 2 routes", w.Body.String())
+	}
+	// Routes are sorted by host, so the endpoint itself comes first
+	if got[0]["pattern"] != "/_routes" || got[0]["method"] != "GET" || got[0]["host"] != nil {
+		t.Errorf("first route = %v, want GET /_routes without a host", got[0])
+	}
+	item := got[1]
+	if item["method"] != "POST" || item["host"] != "api.example.com" || item["pattern"] != "/items" ||
+		item["handler"] != "github.com/kashari/draupnir.showUser" || item["meta"].(map[string]any)["public"] != true {
+		t.Errorf("second route = %v", item)
+	}
+	if _, ok := item["name"]; ok {
+		t.Errorf("unnamed route reported with a name: %v", item)
+	}
+}
+
+func TestAnyAndMatch(t *testing.T) {
+	echo := func(c *Context) error {
+		c.Writer.Header().Set("X-Method", c.Request.Method)
+		return c.String(http.StatusOK, c.Request.Method)
+	}
+
+	r := New().WithRequestLogging(false).WithStrictRoutes(false)
+	r.ANY("/any", echo)
+	r.Group("/g").ANY("/any", echo)
+	r.Match([]string{http.MethodGet, http.MethodPost}, "/match", echo)
+	r.Group("/g").Match([]string{http.MethodPut, http.MethodDelete}, "/match", echo)
+
+	for _, target := range []string{"/any", "/g/any"} {
+		for _, method := range anyMethods {
+			w := serve(r, method, target)
+			if w.Code != http.StatusOK || w.Header().Get("X-Method") != method {
+				t.Errorf("%s %s = %d, handled as %q", method, target, w.Code, w.Header().Get("X-Method"))
+			}
+		}
+	}
+
+	tests := []struct {
+		method, target string
+		code           int
+	}{
+		{http.MethodGet, "/match", http.StatusOK},
+		{http.MethodPost, "/match", http.StatusOK},
+		{http.MethodPut, "/match", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/g/match", http.StatusOK},
+		{http.MethodDelete, "/g/match", http.StatusOK},
+		{http.MethodGet, "/g/match", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if w := serve(r, tt.method, tt.target); w.Code != tt.code {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.target, w.Code, tt.code)
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		t.Fatalf("Err() = %v before the duplicates", err)
+	}
+	r.GET("/any", echo)
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "route GET /any") || !strings.Contains(err.Error(), "duplicates") {
+		t.Errorf("Err() = %v, want GET /any reported as a duplicate of ANY", err)
+	}
+	r.Group("/g").POST("/any", echo)
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "route POST /g/any") {
+		t.Errorf("Err() = %v, want POST /g/any reported as a duplicate of ANY", err)
+	}
+	r.Match([]string{http.MethodPatch, http.MethodPost}, "/match", echo)
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "route POST /match") {
+		t.Errorf("Err() = %v, want POST /match reported as a duplicate", err)
+	}
+	// A failed Match registers none of its methods
+	if w := serve(r, http.MethodPatch, "/match"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PATCH /match = %d after the failed Match, want 405", w.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package draupnir
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// valueStore holds the values set on a Context. It is shared with the request
+// context installed by the first Set, so it outlives the pooled Context.
+type valueStore struct {
+	mu sync.RWMutex
+	m  map[any]any
+}
+
+func (s *valueStore) get(key any) (any, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.m[key]
+	return val, ok
+}
+
+func (s *valueStore) set(key, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = value
+}
+
+// storeContext is a request context that resolves the keys of a valueStore
+// before consulting its parent.
+type storeContext struct {
+	context.Context
+	store *valueStore
+}
+
+func (ctx storeContext) Value(key any) any {
+	if val, ok := ctx.store.get(key); ok {
+		return val
+	}
+	return ctx.Context.Value(key)
+}
+
+// values returns the store of c, creating it on first use. Creating the store
+// replaces c.Request with a copy whose context resolves the store keys, so that
+// code receiving only c.Request.Context() sees the values.
+func (c *Context) values() *valueStore {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+
+	if c.store == nil {
+		c.store = &valueStore{m: make(map[any]any)}
+		c.Request = c.Request.WithContext(storeContext{c.Request.Context(), c.store})
+	}
+	return c.store
+}
+
+// lookup returns the value stored under key, without creating the store.
+func (c *Context) lookup(key any) (any, bool) {
+	c.storeMu.Lock()
+	s := c.store
+	c.storeMu.Unlock()
+	return s.get(key)
+}
+
+// MustGet returns the value stored under key and panics if there is none.
+func (c *Context) MustGet(key string) any {
+	val, ok := c.lookup(key)
+	if !ok {
+		panic(fmt.Sprintf("draupnir: key %q does not exist", key))
+	}
+	return val
+}
+
+// GetAs returns the value stored under key if it has type T.
+//
+//	user, ok := draupnir.GetAs[*User](ctx, "user")
+func GetAs[T any](c *Context, key string) (T, bool) {
+	val, _ := c.lookup(key)
+	v, ok := val.(T)
+	return v, ok
+}
+
+// Key is a typed key for the per-request store. Keys are compared by identity,
+// so keys of different packages never collide, even when they share a name.
+//
+//	var UserKey = draupnir.NewKey[*User]("user")
+//
+//	UserKey.Set(ctx, user)
+//	user, ok := UserKey.Get(ctx)
+//
+// A Key also retrieves its value from the request context, as in
+// ctx.Request.Context().Value(UserKey).
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new key for values of type T.
+// The name is only used for messages.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// Set stores value under k.
+func (k *Key[T]) Set(c *Context, value T) {
+	c.values().set(k, value)
+}
+
+// Get returns the value stored under k.
+func (k *Key[T]) Get(c *Context) (T, bool) {
+	val, _ := c.lookup(k)
+	v, ok := val.(T)
+	return v, ok
+}
+
+// MustGet returns the value stored under k and panics if there is none.
+func (k *Key[T]) MustGet(c *Context) T {
+	v, ok := k.Get(c)
+	if !ok {
+		panic(fmt.Sprintf("draupnir: key %q does not exist", k.name))
+	}
+	return v
+}
@@ -0,0 +1,139 @@
+package draupnir
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// newTestContext returns a Context for a GET request to target, outside of any router pipeline.
+func newTestContext(target string) *Context {
+	c := &Context{}
+	c.reset(New(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	return c
+}
+
+func TestStoreCreatedOnFirstSet(t *testing.T) {
+	c := newTestContext("/")
+	req := c.Request
+	if _, ok := c.Get("user"); ok || c.store != nil || c.Request != req {
+		t.Fatal("Get created the store")
+	}
+
+	c.Set("user", "gopher")
+	if c.store == nil || c.Request == req {
+		t.Fatal("Set did not create the store and replace the request")
+	}
+	if v, ok := c.Get("user"); !ok || v != "gopher" {
+		t.Errorf("Get(user) = %v, %v, want gopher", v, ok)
+	}
+	if v := c.Request.Context().Value("user"); v != "gopher" {
+		t.Errorf("Request.Context().Value(user) = %v, want gopher", v)
+	}
+
+	// Later values are visible through the context installed by the first Set
+	ctx := c.Request.Context()
+	c.Set("role", "admin")
+	if v := ctx.Value("role"); v != "admin" {
+		t.Errorf("Value(role) = %v on the earlier request context, want admin", v)
+	}
+}
+
+func TestGetAs(t *testing.T) {
+	c := newTestContext("/")
+	c.Set("count", 3)
+	if v, ok := GetAs[int](c, "count"); !ok || v != 3 {
+		t.Errorf("GetAs[int](count) = %v, %v, want 3", v, ok)
+	}
+	if v, ok := GetAs[string](c, "count"); ok || v != "" {
+		t.Errorf("GetAs[string](count) = %q, %v, want a zero value", v, ok)
+	}
+	if _, ok := GetAs[int](c, "missing"); ok {
+		t.Error("GetAs[int](missing) found a value")
+	}
+	if c.GetInt("count") != 3 || c.GetString("count") != "" {
+		t.Errorf("GetInt = %d, GetString = %q", c.GetInt("count"), c.GetString("count"))
+	}
+}
+
+func TestMustGet(t *testing.T) {
+	c := newTestContext("/")
+	c.Set("user", "gopher")
+	if v := c.MustGet("user"); v != "gopher" {
+		t.Errorf("MustGet(user) = %v, want gopher", v)
+	}
+
+	userKey := NewKey[string]("user")
+	userKey.Set(c, "typed")
+	if v := userKey.MustGet(c); v != "typed" {
+		t.Errorf("Key.MustGet = %q, want typed", v)
+	}
+
+	for name, get := range map[string]func(){
+		"Context.MustGet": func() { c.MustGet("missing") },
+		"Key.MustGet":     func() { NewKey[int]("missing").MustGet(c) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic for a missing key", name)
+				}
+			}()
+			get()
+		}()
+	}
+}
+
+func TestKeyIdentity(t *testing.T) {
+	// Keys declared by different packages may share a name
+	a, b := NewKey[string]("user"), NewKey[string]("user")
+	c := newTestContext("/")
+	a.Set(c, "from a")
+	c.Set("user", "from string")
+
+	if v, ok := a.Get(c); !ok || v != "from a" {
+		t.Errorf("a.Get = %q, %v, want \"from a\"", v, ok)
+	}
+	if v, ok := b.Get(c); ok {
+		t.Errorf("b.Get = %q, want no value", v)
+	}
+	if c.GetString("user") != "from string" {
+		t.Errorf("GetString(user) = %q, want \"from string\"", c.GetString("user"))
+	}
+	if v := c.Request.Context().Value(a); v != "from a" {
+		t.Errorf("Request.Context().Value(a) = %v, want \"from a\"", v)
+	}
+	if a.String() != "user" {
+		t.Errorf("String() = %q, want user", a.String())
+	}
+}
+
+func TestStoreConcurrentAccess(t *testing.T) {
+	c := newTestContext("/")
+	countKey := NewKey[int]("count")
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				key := strconv.Itoa(g) + "." + strconv.Itoa(i)
+				c.Set(key, i)
+				countKey.Set(c, i)
+				if c.GetInt(key) != i {
+					t.Errorf("GetInt(%s) = %d, want %d", key, c.GetInt(key), i)
+				}
+				countKey.Get(c)
+				c.Request.Context().Value(countKey)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.GetInt("7.99") != 99 {
+		t.Errorf("GetInt(7.99) = %d, want 99", c.GetInt("7.99"))
+	}
+}
@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// namedConstraints are the constraints that can be referenced by name in a pattern.
+var namedConstraints = map[string]func(string) bool{
+	"int": func(s string) bool {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err == nil
+	},
+	"uint": func(s string) bool {
+		_, err := strconv.ParseUint(s, 10, 64)
+		return err == nil
+	},
+	"float": func(s string) bool {
+		_, err := strconv.ParseFloat(s, 64)
+		return err == nil
+	},
+	"uuid": isUUID,
+}
+
+// numericConstraints are the named constraints accepting overlapping values: "7" is an
+// int, a uint and a float.
+var numericConstraints = map[string]bool{"int": true, "uint": true, "float": true}
+
+// constraintsOverlap reports whether two different constraints are known to accept
+// common values. Overlaps involving regular expressions are not detected.
+func constraintsOverlap(a, b string) bool {
+	return a != b && numericConstraints[a] && numericConstraints[b]
+}
+
+// compileConstraint turns a constraint expression into a segment predicate.
+// Names in namedConstraints take precedence; anything else is a regular
+// expression anchored to the whole segment.
+func compileConstraint(expr string) (func(string) bool, error) {
+	if fn, ok := namedConstraints[expr]; ok {
+		return fn, nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// isUUID reports whether s is a UUID in its canonical 8-4-4-4-12 hex form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package tree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompileConstraint(t *testing.T) {
+	tests := []struct {
+		expr   string
+		accept []string
+		reject []string
+	}{
+		{"int", []string{"0", "42", "-7", "9223372036854775807"}, []string{"", "4.2", "x", "9223372036854775808", "1e3"}},
+		{"uint", []string{"0", "42", "18446744073709551615"}, []string{"-1", "4.2", "18446744073709551616"}},
+		{"float", []string{"1", "-4.2", "1e3", ".5"}, []string{"", "abc", "1..2"}},
+		{"uuid", []string{"123e4567-e89b-12d3-a456-426614174000", "123E4567-E89B-12D3-A456-426614174000"},
+			[]string{"123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g", "123e4567-e89b-12d3-a456_426614174000"}},
+		// Regular expressions must match the whole segment
+		{"[a-z]+", []string{"abc"}, []string{"", "abc1", "1abc"}},
+		{"a|b", []string{"a", "b"}, []string{"ab", "xa"}},
+	}
+	for _, tt := range tests {
+		accepts, err := compileConstraint(tt.expr)
+		if err != nil {
+			t.Fatalf("compileConstraint(%q): %v", tt.expr, err)
+		}
+		for _, s := range tt.accept {
+			if !accepts(s) {
+				t.Errorf("<%s> rejects %q", tt.expr, s)
+			}
+		}
+		for _, s := range tt.reject {
+			if accepts(s) {
+				t.Errorf("<%s> accepts %q", tt.expr, s)
+			}
+		}
+	}
+
+	if _, err := compileConstraint("[a-"); err == nil {
+		t.Error("compileConstraint([a-) succeeded, want an error")
+	}
+	if err := New().InsertRoute("/posts/:slug<[a-", nil); err == nil {
+		t.Error("InsertRoute with an unterminated constraint succeeded")
+	}
+	if err := New().InsertRoute("/posts/:slug<(>", nil); err == nil {
+		t.Error("InsertRoute with an invalid regular expression succeeded")
+	}
+}
+
+func TestMatchConstraints(t *testing.T) {
+	tr := newTree(t,
+		"/items/:id<int>",
+		"/items/:id<uuid>",
+		"/items/:slug<[a-z-]+>",
+		"/items/:any",
+		"/posts/:id<int>/comments",
+		"/posts/:slug/comments",
+		"/files/:name<.+\\.txt>/raw",
+	)
+
+	tests := []struct {
+		path    string
+		pattern string
+		param   Param
+	}{
+		// Same-shape routes are told apart by their constraints
+		{"/items/42", "/items/:id<int>", Param{Key: "id", Value: "42", Constraint: "int"}},
+		{"/items/123e4567-e89b-12d3-a456-426614174000", "/items/:id<uuid>",
+			Param{Key: "id", Value: "123e4567-e89b-12d3-a456-426614174000", Constraint: "uuid"}},
+		{"/items/hello-world", "/items/:slug<[a-z-]+>", Param{Key: "slug", Value: "hello-world", Constraint: "[a-z-]+"}},
+		// The unconstrained parameter is tried last
+		{"/items/Hello_1", "/items/:any", Param{Key: "any", Value: "Hello_1"}},
+		{"/posts/7/comments", "/posts/:id<int>/comments", Param{Key: "id", Value: "7", Constraint: "int"}},
+		{"/posts/seven/comments", "/posts/:slug/comments", Param{Key: "slug", Value: "seven"}},
+		{"/files/a.txt/raw", "/files/:name<.+\\.txt>/raw", Param{Key: "name", Value: "a.txt", Constraint: ".+\\.txt"}},
+	}
+	for _, tt := range tests {
+		val, params, found := tr.Match(tt.path, nil)
+		if !found || val != tt.pattern {
+			t.Errorf("Match(%q) = %v, %v, want %q", tt.path, val, found, tt.pattern)
+			continue
+		}
+		if !slices.Equal(params, []Param{tt.param}) {
+			t.Errorf("Match(%q) params = %v, want %v", tt.path, params, tt.param)
+		}
+	}
+
+	if val, _, found := tr.Match("/files/a.png/raw", nil); found {
+		t.Errorf("Match(/files/a.png/raw) = %v, want no match", val)
+	}
+}
+
+func TestOverlappingConstraints(t *testing.T) {
+	tests := []struct {
+		existing, pattern string
+		conflict          bool
+	}{
+		{"/u/:id<int>", "/u/:id<uint>", true},
+		{"/u/:id<uint>", "/u/:n<float>", true},
+		{"/u/:id<float>/x", "/u/:id<int>", true},
+		{"/u/:id<int>", "/u/:id<uuid>", false},
+		{"/u/:id<int>", "/u/:id", false},
+		// Overlaps with regular expressions are not detected
+		{"/u/:id<int>", "/u/:n<[0-9]+>", false},
+	}
+	for _, tt := range tests {
+		tr := newTree(t, tt.existing)
+		err := tr.InsertRoute(tt.pattern, tt.pattern)
+		if got := err != nil; got != tt.conflict {
+			t.Errorf("InsertRoute(%q) after %q = %v, want conflict %v", tt.pattern, tt.existing, err, tt.conflict)
+		}
+	}
+
+	// A stale overlapping parameter is replaced
+	tr := newTree(t, "/u/:id<int>")
+	tr.DeleteRoute("/u/:id<int>")
+	if err := tr.InsertRoute("/u/:id<uint>", "uint"); err != nil {
+		t.Fatalf("InsertRoute(/u/:id<uint>) after deleting /u/:id<int>: %v", err)
+	}
+	if val, params, _ := tr.Match("/u/7", nil); val != "uint" || params[0].Constraint != "uint" {
+		t.Errorf("Match(/u/7) = %v, %v, want the uint route", val, params)
+	}
+}
@@ -1,11 +1,39 @@
 // Package radix provides a radix tree implementation for efficient string-based lookups.
 package tree
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// nodeKind distinguishes literal nodes from wildcard nodes.
+type nodeKind uint8
+
+const (
+	// static nodes match their key literally.
+	static nodeKind = iota
+	// param nodes (":name") match a single non-empty path segment.
+	param
+	// catchAll nodes ("*name") match the remainder of the path.
+	catchAll
+)
+
 // Node represents a node in the radix tree.
 type Node struct {
 	// The key segment stored at this node
 	key string
 
+	// The kind of segment this node matches
+	kind nodeKind
+
+	// Wildcard name, constraint expression and compiled constraint for param and catch-all nodes
+	name       string
+	constraint string
+	accepts    func(string) bool
+
 	// The value stored at this node
 	value any
 
@@ -14,133 +42,596 @@ type Node struct {
 
 	// Child nodes indexed by their first byte
 	children map[byte]*Node
+
+	// Parameter children, tried after the static children.
+	// Constrained parameters come before the unconstrained one.
+	params []*Node
+
+	// Catch-all child, tried last
+	catchAll *Node
+
+	// The tree allowed to modify this node in place; other trees copy it first
+	owner *owner
+}
+
+// owner identifies the nodes a tree may modify in place. Clones share their
+// nodes until either of them modifies one, which copies the path to it.
+type owner struct{ _ byte }
+
+// Param is a single wildcard value captured by Match.
+type Param struct {
+	Key   string
+	Value string
+
+	// Constraint is the expression the value satisfied, e.g. "int" or "[a-z]+".
+	// It is empty for unconstrained parameters.
+	Constraint string
+}
+
+// ConflictError reports a route pattern that is ambiguous with a route already
+// in the tree, e.g. "/users/:name" when "/users/:id" exists.
+type ConflictError struct {
+	// Pattern is the pattern that could not be inserted
+	Pattern string
+
+	// Existing is the conflicting pattern already in the tree
+	Existing string
+
+	// Value is the value stored for Existing
+	Value any
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("route %q conflicts with existing route %q", e.Pattern, e.Existing)
 }
 
 // Tree represents a radix tree data structure.
 type Tree struct {
-	root *Node
-	size int
+	root  *Node
+	size  int
+	owner *owner
 }
 
 // New creates a new radix tree.
 func New() *Tree {
-	return &Tree{
-		root: &Node{
-			children: make(map[byte]*Node),
-		},
+	t := &Tree{owner: new(owner)}
+	t.root = t.newNode("", static)
+	return t
+}
+
+// newNode creates an empty node with the given key.
+func newNode(key string, kind nodeKind) *Node {
+	return &Node{
+		key:      key,
+		kind:     kind,
+		children: make(map[byte]*Node),
+	}
+}
+
+// newNode creates an empty node owned by t.
+func (t *Tree) newNode(key string, kind nodeKind) *Node {
+	n := newNode(key, kind)
+	n.owner = t.owner
+	return n
+}
+
+// own returns n if t may modify it in place, or a copy of n owned by t.
+// The copy shares its descendants with n.
+func (t *Tree) own(n *Node) *Node {
+	if n.owner == t.owner {
+		return n
+	}
+	c := *n
+	c.owner = t.owner
+	c.children = make(map[byte]*Node, len(n.children))
+	for b, child := range n.children {
+		c.children[b] = child
 	}
+	c.params = slices.Clone(n.params)
+	return &c
 }
 
 // Insert adds a new key-value pair to the tree.
+// The key is stored literally; use InsertRoute for patterns with wildcards.
 func (t *Tree) Insert(key string, value any) {
 	if key == "" {
 		return
 	}
 
-	t.insert(t.root, key, value)
-	t.size++
+	t.root = t.own(t.root)
+	t.setValue(t.insert(t.root, key), value)
 }
 
-// insert recursively adds a key-value pair to the tree.
-func (t *Tree) insert(n *Node, key string, value any) {
-	// Handle empty tree
-	if len(n.children) == 0 {
-		n.children[key[0]] = &Node{
-			key:      key,
-			value:    value,
-			hasValue: true,
-			children: make(map[byte]*Node),
-		}
-		return
+// setValue stores a value at a node, keeping the size up to date.
+func (t *Tree) setValue(n *Node, value any) {
+	if !n.hasValue {
+		t.size++
 	}
+	n.value = value
+	n.hasValue = true
+}
 
+// insert returns the static node for key below n, creating and splitting nodes as needed.
+// n must be owned by t, and so are the nodes on the way to the returned one.
+func (t *Tree) insert(n *Node, key string) *Node {
 	// Find the matching child
 	c, ok := n.children[key[0]]
 	if !ok {
 		// No matching child, create a new one
-		n.children[key[0]] = &Node{
-			key:      key,
-			value:    value,
-			hasValue: true,
-			children: make(map[byte]*Node),
-		}
-		return
+		c = t.newNode(key, static)
+		n.children[key[0]] = c
+		return c
 	}
 
 	// Find common prefix length
 	prefixLen := commonPrefixLen(key, c.key)
 
-	// If the key is already in the tree
-	if prefixLen == len(key) && prefixLen == len(c.key) {
-		c.value = value
-		c.hasValue = true
-		return
+	// If the key diverges inside the child key, split the child at the common prefix
+	c = t.own(c)
+	if prefixLen < len(c.key) {
+		split := t.newNode(c.key[:prefixLen], static)
+		c.key = c.key[prefixLen:]
+		split.children[c.key[0]] = c
+		c = split
 	}
+	n.children[key[0]] = c
 
-	// If the key is a prefix of the child key, split the child
+	// The key ends at this node
 	if prefixLen == len(key) {
-		// Key is a prefix of child, so we need to split
-		childSuffix := c.key[prefixLen:]
-
-		// Update current child to have the new value
-		oldValue := c.value
-		oldHasValue := c.hasValue
-		oldChildren := c.children
-
-		c.key = key
-		c.value = value
-		c.hasValue = true
-
-		// Create a new child with the remaining suffix
-		c.children = make(map[byte]*Node)
-		if childSuffix != "" {
-			c.children[childSuffix[0]] = &Node{
-				key:      childSuffix,
-				value:    oldValue,
-				hasValue: oldHasValue,
-				children: oldChildren,
+		return c
+	}
+
+	// The child key is a prefix of the key, recurse
+	return t.insert(c, key[prefixLen:])
+}
+
+// InsertRoute adds a route pattern to the tree.
+// A segment starting with ':' matches any single path segment and a segment
+// starting with '*' matches the remainder of the path, slashes included. Both
+// capture the matched text under the name that follows the prefix character.
+// A catch-all must be the last segment of the pattern.
+//
+// A parameter may be followed by a constraint in angle brackets, either one of
+// the named constraints (int, uint, float, uuid) or a regular expression that
+// must match the whole segment: "/users/:id<int>", "/posts/:slug<[a-z0-9-]+>".
+// Parameters with different constraints may share a position in the tree;
+// parameters or catch-alls with the same constraint but different names are
+// ambiguous and reported as a *ConflictError, as are parameters whose named
+// constraints overlap, such as int and uint. Overlaps between regular
+// expressions, or a regular expression and a named constraint, are not
+// detected: the parameter inserted first wins when both match.
+func (t *Tree) InsertRoute(pattern string, value any) error {
+	n, err := t.route(pattern, true)
+	if err != nil {
+		return err
+	}
+	t.setValue(n, value)
+	return nil
+}
+
+// GetRoute retrieves the value stored for a pattern exactly as it was inserted.
+func (t *Tree) GetRoute(pattern string) (any, bool) {
+	n, err := t.route(pattern, false)
+	if err != nil || n == nil || !n.hasValue {
+		return nil, false
+	}
+	return n.value, true
+}
+
+// route walks the nodes of a pattern, creating them when create is set.
+// Without create it returns nil if the pattern is not in the tree. With create,
+// the nodes on the way are copied as needed, so the returned node is owned by t.
+func (t *Tree) route(pattern string, create bool) (*Node, error) {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if create {
+		t.root = t.own(t.root)
+	}
+	n := t.root
+	offset := 0
+	for _, seg := range segments {
+		prefix := pattern[:offset]
+		offset += len(seg.key)
+
+		switch seg.kind {
+		case static:
+			if create {
+				n = t.insert(n, seg.key)
+			} else if n = findFrom(n, seg.key); n == nil {
+				return nil, nil
 			}
+
+		case catchAll:
+			if n.catchAll != nil && n.catchAll.key != seg.key {
+				if !create {
+					return nil, nil
+				}
+				if err := t.conflict(n.catchAll, prefix, pattern); err != nil {
+					return nil, err
+				}
+				n.catchAll = nil
+			}
+			if n.catchAll == nil {
+				if !create {
+					return nil, nil
+				}
+				seg.owner = t.owner
+				n.catchAll = seg
+			}
+			if create {
+				n.catchAll = t.own(n.catchAll)
+			}
+			n = n.catchAll
+
+		case param:
+			i := n.paramIndex(seg.constraint)
+			if i >= 0 && n.params[i].name != seg.name {
+				if !create {
+					return nil, nil
+				}
+				if err := t.conflict(n.params[i], prefix, pattern); err != nil {
+					return nil, err
+				}
+				n.params = slices.Delete(n.params, i, i+1)
+				i = -1
+			}
+			if i < 0 {
+				if !create {
+					return nil, nil
+				}
+				if err := t.replaceOverlapping(n, prefix, pattern, seg.constraint); err != nil {
+					return nil, err
+				}
+				seg.owner = t.owner
+				i = n.addParam(seg)
+			}
+			if create {
+				n.params[i] = t.own(n.params[i])
+			}
+			n = n.params[i]
 		}
-		return
 	}
+	return n, nil
+}
 
-	// If the child key is a prefix of the key, recurse
-	if prefixLen == len(c.key) {
-		remainder := key[prefixLen:]
-		t.insert(c, remainder, value)
-		return
+// DeleteRoute removes a pattern inserted with InsertRoute.
+// Nodes left without values stay in the tree; wildcard nodes among them no
+// longer conflict with new patterns.
+func (t *Tree) DeleteRoute(pattern string) bool {
+	if _, found := t.GetRoute(pattern); !found {
+		return false
 	}
+	// The pattern is in the tree, so this only copies the nodes leading to it
+	n, _ := t.route(pattern, true)
+	n.value = nil
+	n.hasValue = false
+	t.size--
+	return true
+}
 
-	// Neither is a prefix of the other, so we need to split
-	// Create a new node with the common prefix
-	newNode := &Node{
-		key:      key[:prefixLen],
-		hasValue: false,
-		children: make(map[byte]*Node),
+// Clone returns a copy of the tree that can be modified without affecting t.
+// Values are shared between both trees. Cloning takes constant time: both trees
+// share their nodes, and copy only the nodes on the path to a modification.
+// Like modifications, Clone must not run concurrently with other changes to t.
+func (t *Tree) Clone() *Tree {
+	// Neither tree may modify the shared nodes in place anymore
+	t.owner = new(owner)
+	return &Tree{
+		root:  t.root,
+		size:  t.size,
+		owner: new(owner),
 	}
+}
 
-	// Add the existing child with its suffix
-	childSuffix := c.key[prefixLen:]
-	c.key = childSuffix
-	newNode.children[childSuffix[0]] = c
+// replaceOverlapping removes the parameter children of n whose constraint overlaps
+// constraint, or reports a conflict if routes are stored below one of them.
+// n must be owned by t.
+func (t *Tree) replaceOverlapping(n *Node, prefix, pattern, constraint string) error {
+	for i := 0; i < len(n.params); {
+		if !constraintsOverlap(n.params[i].constraint, constraint) {
+			i++
+			continue
+		}
+		if err := t.conflict(n.params[i], prefix, pattern); err != nil {
+			return err
+		}
+		n.params = slices.Delete(n.params, i, i+1)
+	}
+	return nil
+}
+
+// conflict reports the first route stored below the wildcard node w, which
+// ambiguously overlaps pattern. A wildcard with no routes below it is stale
+// and may be replaced, so conflict returns nil for it.
+func (t *Tree) conflict(w *Node, prefix, pattern string) error {
+	var err error
+	t.walk(w, prefix, func(key string, value any) bool {
+		err = &ConflictError{Pattern: pattern, Existing: key, Value: value}
+		return true
+	})
+	return err
+}
+
+// parsePattern splits a pattern into literal runs and detached wildcard nodes.
+func parsePattern(pattern string) ([]*Node, error) {
+	if pattern == "" {
+		return nil, errors.New("empty route pattern")
+	}
+
+	var segments []*Node
+	for i := 0; i < len(pattern); {
+		start := wildcardIndex(pattern, i)
+		if start > i {
+			// Literal run up to the next wildcard
+			segments = append(segments, &Node{key: pattern[i:start]})
+			i = start
+			continue
+		}
 
-	// Add the new key with its suffix
-	keySuffix := key[prefixLen:]
-	if keySuffix != "" {
-		newNode.children[keySuffix[0]] = &Node{
-			key:      keySuffix,
-			value:    value,
-			hasValue: true,
-			children: make(map[byte]*Node),
+		w, err := parseWildcard(pattern, i)
+		if err != nil {
+			return nil, err
 		}
+		segments = append(segments, w)
+		i += len(w.key)
+	}
+	return segments, nil
+}
+
+// parseWildcard parses the wildcard segment starting at pattern[i] into a detached node.
+func parseWildcard(pattern string, i int) (*Node, error) {
+	end := strings.IndexByte(pattern[i:], '/')
+	if end < 0 {
+		end = len(pattern)
 	} else {
-		// The key ends at the split point
-		newNode.value = value
-		newNode.hasValue = true
+		end += i
 	}
 
-	// Replace the child in the parent
-	n.children[key[0]] = newNode
+	// Constraints may contain slashes, so look for the '>' that closes the segment
+	lt := strings.IndexByte(pattern[i:end], '<')
+	if lt >= 0 {
+		lt += i
+		end = -1
+		for k := lt + 1; k < len(pattern); k++ {
+			if pattern[k] == '>' && (k+1 == len(pattern) || pattern[k+1] == '/') {
+				end = k + 1
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated constraint in %q", pattern)
+		}
+	}
+
+	w := newNode(pattern[i:end], param)
+	w.name = w.key[1:]
+	if lt >= 0 {
+		w.name = pattern[i+1 : lt]
+		w.constraint = pattern[lt+1 : end-1]
+	}
+	if w.name == "" {
+		return nil, fmt.Errorf("wildcard in %q must be named", pattern)
+	}
+
+	if pattern[i] == '*' {
+		if end != len(pattern) {
+			return nil, fmt.Errorf("catch-all %q in %q must be the last segment", w.key, pattern)
+		}
+		if lt >= 0 {
+			return nil, fmt.Errorf("catch-all %q in %q cannot have a constraint", w.key, pattern)
+		}
+		w.kind = catchAll
+		return w, nil
+	}
+
+	if lt >= 0 {
+		accepts, err := compileConstraint(w.constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q in %q: %w", w.constraint, pattern, err)
+		}
+		w.accepts = accepts
+	}
+	return w, nil
+}
+
+// Build fills the wildcards of pattern with the values returned by lookup,
+// escaping them for use in a URL path. Catch-all values keep their slashes.
+// It fails if a value is missing or does not satisfy its constraint.
+func Build(pattern string, lookup func(name string) (string, bool)) (string, error) {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, seg := range segments {
+		if seg.kind == static {
+			b.WriteString(seg.key)
+			continue
+		}
+
+		value, ok := lookup(seg.name)
+		if !ok {
+			return "", fmt.Errorf("missing value for wildcard %q in %q", seg.name, pattern)
+		}
+
+		if seg.kind == catchAll {
+			for i, part := range strings.Split(value, "/") {
+				if i > 0 {
+					b.WriteByte('/')
+				}
+				b.WriteString(url.PathEscape(part))
+			}
+			continue
+		}
+
+		if value == "" || (seg.accepts != nil && !seg.accepts(value)) {
+			return "", fmt.Errorf("value %q for wildcard %q in %q does not satisfy its constraint", value, seg.name, pattern)
+		}
+		b.WriteString(url.PathEscape(value))
+	}
+	return b.String(), nil
+}
+
+// paramIndex returns the index of the parameter child with the given constraint, or -1.
+func (n *Node) paramIndex(constraint string) int {
+	for i, p := range n.params {
+		if p.constraint == constraint {
+			return i
+		}
+	}
+	return -1
+}
+
+// addParam adds a parameter child, keeping the unconstrained parameter last,
+// and returns its index.
+func (n *Node) addParam(w *Node) int {
+	if w.accepts == nil || len(n.params) == 0 || n.params[len(n.params)-1].accepts != nil {
+		n.params = append(n.params, w)
+		return len(n.params) - 1
+	}
+	last := len(n.params) - 1
+	n.params = append(n.params[:last], w, n.params[last])
+	return last
+}
+
+// wildcardIndex returns the index of the first wildcard at or after from,
+// or len(pattern) if there is none. Wildcards must start a path segment.
+func wildcardIndex(pattern string, from int) int {
+	for i := from; i < len(pattern); i++ {
+		if (pattern[i] == ':' || pattern[i] == '*') && i > 0 && pattern[i-1] == '/' {
+			return i
+		}
+	}
+	return len(pattern)
+}
+
+// Match finds the route matching a request path.
+// Static children take priority over parameters, which take priority over
+// catch-alls. Captured wildcard values are appended to params.
+func (t *Tree) Match(path string, params []Param) (any, []Param, bool) {
+	if path == "" {
+		return nil, params, false
+	}
+
+	n, params := match(t.root, path, params)
+	if n == nil {
+		return nil, params, false
+	}
+	return n.value, params, true
+}
+
+// match recursively matches path below n, backtracking when a branch fails.
+func match(n *Node, path string, params []Param) (*Node, []Param) {
+	if path == "" {
+		if n.hasValue {
+			return n, params
+		}
+		if n.catchAll != nil && n.catchAll.hasValue {
+			return n.catchAll, append(params, Param{Key: n.catchAll.name})
+		}
+		return nil, params
+	}
+
+	if c, ok := n.children[path[0]]; ok && strings.HasPrefix(path, c.key) {
+		if found, p := match(c, path[len(c.key):], params); found != nil {
+			return found, p
+		}
+	}
+
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			segment := path[:end]
+			for _, c := range n.params {
+				if c.accepts != nil && !c.accepts(segment) {
+					continue
+				}
+				p := append(params, Param{Key: c.name, Value: segment, Constraint: c.constraint})
+				if found, p := match(c, path[end:], p); found != nil {
+					return found, p
+				}
+			}
+		}
+	}
+
+	if n.catchAll != nil && n.catchAll.hasValue {
+		return n.catchAll, append(params, Param{Key: n.catchAll.name, Value: path})
+	}
+	return nil, params
+}
+
+// MatchFold finds the route matching path like Match, but compares static
+// segments case-insensitively. It returns the path spelled as registered, with
+// wildcard values left unchanged.
+func (t *Tree) MatchFold(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+
+	buf, ok := matchFold(t.root, path, make([]byte, 0, len(path)))
+	return string(buf), ok
+}
+
+// matchFold recursively matches path below n, appending the canonical spelling to buf.
+func matchFold(n *Node, path string, buf []byte) ([]byte, bool) {
+	if path == "" {
+		return buf, n.hasValue || (n.catchAll != nil && n.catchAll.hasValue)
+	}
+
+	lower, upper := foldCase(path[0])
+	for _, b := range [2]byte{lower, upper} {
+		c, ok := n.children[b]
+		if !ok || len(c.key) > len(path) || !strings.EqualFold(path[:len(c.key)], c.key) {
+			continue
+		}
+		if out, ok := matchFold(c, path[len(c.key):], append(buf, c.key...)); ok {
+			return out, true
+		}
+		if lower == upper {
+			break
+		}
+	}
+
+	if len(n.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			segment := path[:end]
+			for _, c := range n.params {
+				if c.accepts != nil && !c.accepts(segment) {
+					continue
+				}
+				if out, ok := matchFold(c, path[end:], append(buf, segment...)); ok {
+					return out, true
+				}
+			}
+		}
+	}
+
+	if n.catchAll != nil && n.catchAll.hasValue {
+		return append(buf, path...), true
+	}
+	return buf, false
+}
+
+// foldCase returns the lower and upper case forms of an ASCII letter, or b twice.
+func foldCase(b byte) (byte, byte) {
+	switch {
+	case 'a' <= b && b <= 'z':
+		return b, b - 'a' + 'A'
+	case 'A' <= b && b <= 'Z':
+		return b - 'A' + 'a', b
+	}
+	return b, b
 }
 
 // Get retrieves a value from the tree.
@@ -159,32 +650,32 @@ func (t *Tree) findNode(key string) (*Node, bool) {
 		return nil, false
 	}
 
-	n := t.root
-	for {
-		// If we're at a leaf node and haven't matched the key, it's not in the tree
-		if len(n.children) == 0 {
-			return nil, false
-		}
+	n := findFrom(t.root, key)
+	return n, n != nil
+}
 
+// findFrom looks up a static key below n.
+func findFrom(n *Node, key string) *Node {
+	for {
 		// Find the matching child
 		c, ok := n.children[key[0]]
 		if !ok {
-			return nil, false
+			return nil
 		}
 
 		// If the child's key is longer than the remaining key, it's not in the tree
 		if len(c.key) > len(key) {
-			return nil, false
+			return nil
 		}
 
 		// Check if the child's key matches the start of the key
 		if key[:len(c.key)] != c.key {
-			return nil, false
+			return nil
 		}
 
 		// If we've matched the entire key, return the child
 		if len(c.key) == len(key) {
-			return c, true
+			return c
 		}
 
 		// Move to the next part of the key
@@ -195,6 +686,10 @@ func (t *Tree) findNode(key string) (*Node, bool) {
 
 // Delete removes a key from the tree.
 func (t *Tree) Delete(key string) bool {
+	if _, found := t.Get(key); !found {
+		return false
+	}
+	t.root = t.own(t.root)
 	deleted := t.delete(t.root, key)
 	if deleted {
 		t.size--
@@ -202,7 +697,7 @@ func (t *Tree) Delete(key string) bool {
 	return deleted
 }
 
-// delete recursively removes a key from the tree.
+// delete recursively removes a key from the tree. n must be owned by t.
 func (t *Tree) delete(n *Node, key string) bool {
 	if key == "" {
 		return false
@@ -218,10 +713,13 @@ func (t *Tree) delete(n *Node, key string) bool {
 		return false
 	}
 
+	c = t.own(c)
+	n.children[key[0]] = c
+
 	// If we've matched the whole key
 	if len(key) == len(c.key) {
 		// If this node has children, just mark it as not having a value
-		if len(c.children) > 0 {
+		if c.hasChildren() {
 			if !c.hasValue {
 				return false
 			}
@@ -239,17 +737,13 @@ func (t *Tree) delete(n *Node, key string) bool {
 	remainder := key[len(c.key):]
 	if t.delete(c, remainder) {
 		// If the child now has no value and no children, remove it
-		if !c.hasValue && len(c.children) == 0 {
+		if !c.hasValue && !c.hasChildren() {
 			delete(n.children, key[0])
-		} else if !c.hasValue && len(c.children) == 1 {
-			for b, grandchild := range c.children {
-				n.children[key[0]] = &Node{
-					key:      c.key + grandchild.key,
-					value:    grandchild.value,
-					hasValue: grandchild.hasValue,
-					children: grandchild.children,
-				}
-				delete(c.children, b)
+		} else if !c.hasValue && len(c.children) == 1 && len(c.params) == 0 && c.catchAll == nil {
+			for _, grandchild := range c.children {
+				merged := t.own(grandchild)
+				merged.key = c.key + grandchild.key
+				n.children[key[0]] = merged
 			}
 		}
 		return true
@@ -257,6 +751,11 @@ func (t *Tree) delete(n *Node, key string) bool {
 	return false
 }
 
+// hasChildren reports whether any static or wildcard child hangs off n.
+func (n *Node) hasChildren() bool {
+	return len(n.children) > 0 || len(n.params) > 0 || n.catchAll != nil
+}
+
 // Size returns the number of keys in the tree.
 func (t *Tree) Size() int {
 	return t.size
@@ -281,6 +780,14 @@ func (t *Tree) walk(n *Node, prefix string, fn func(key string, value interface{
 			return true
 		}
 	}
+	for _, c := range n.params {
+		if t.walk(c, prefix+n.key, fn) {
+			return true
+		}
+	}
+	if n.catchAll != nil && t.walk(n.catchAll, prefix+n.key, fn) {
+		return true
+	}
 	return false
 }
 
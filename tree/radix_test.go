@@ -0,0 +1,236 @@
+package tree
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func newTree(t *testing.T, patterns ...string) *Tree {
+	t.Helper()
+	tr := New()
+	for _, p := range patterns {
+		if err := tr.InsertRoute(p, p); err != nil {
+			t.Fatalf("InsertRoute(%q): %v", p, err)
+		}
+	}
+	return tr
+}
+
+func TestMatch(t *testing.T) {
+	tr := newTree(t,
+		"/",
+		"/users/new",
+		"/users/:id",
+		"/users/:id/posts",
+		"/files/*path",
+		"/static/css",
+		"/static/*rest",
+		"/a/:x/c",
+		"/a/b/d",
+	)
+
+	tests := []struct {
+		path    string
+		pattern string // "" when nothing matches
+		params  []Param
+	}{
+		{"/", "/", nil},
+		// Static beats param
+		{"/users/new", "/users/new", nil},
+		{"/users/42", "/users/:id", []Param{{Key: "id", Value: "42"}}},
+		// Backtracking out of the static "new" into the param
+		{"/users/newx", "/users/:id", []Param{{Key: "id", Value: "newx"}}},
+		{"/users/ne", "/users/:id", []Param{{Key: "id", Value: "ne"}}},
+		{"/users/42/posts", "/users/:id/posts", []Param{{Key: "id", Value: "42"}}},
+		// An empty segment does not match a param
+		{"/users/", "", nil},
+		{"/users//posts", "", nil},
+		// Param beats catch-all
+		{"/static/css", "/static/css", nil},
+		{"/static/js/app.js", "/static/*rest", []Param{{Key: "rest", Value: "js/app.js"}}},
+		// The catch-all remainder may be empty
+		{"/files/", "/files/*path", []Param{{Key: "path", Value: ""}}},
+		{"/files/a/b", "/files/*path", []Param{{Key: "path", Value: "a/b"}}},
+		{"/files", "", nil},
+		// Backtracking out of a static subtree that fails deeper down
+		{"/a/b/c", "/a/:x/c", []Param{{Key: "x", Value: "b"}}},
+		{"/a/b/d", "/a/b/d", nil},
+		{"/nope", "", nil},
+	}
+	for _, tt := range tests {
+		val, params, found := tr.Match(tt.path, nil)
+		if tt.pattern == "" {
+			if found {
+				t.Errorf("Match(%q) = %v, want no match", tt.path, val)
+			}
+			continue
+		}
+		if !found || val != tt.pattern {
+			t.Errorf("Match(%q) = %v, %v, want %q", tt.path, val, found, tt.pattern)
+			continue
+		}
+		if !slices.Equal(params, tt.params) {
+			t.Errorf("Match(%q) params = %v, want %v", tt.path, params, tt.params)
+		}
+	}
+}
+
+func TestParamBeatsCatchAll(t *testing.T) {
+	tr := newTree(t, "/:name", "/*rest")
+	if val, _, _ := tr.Match("/x", nil); val != "/:name" {
+		t.Errorf("Match(/x) = %v, want /:name", val)
+	}
+	if val, _, _ := tr.Match("/x/y", nil); val != "/*rest" {
+		t.Errorf("Match(/x/y) = %v, want /*rest", val)
+	}
+}
+
+func TestInsertSplitsNodes(t *testing.T) {
+	// Shorter static routes inserted after longer ones split the existing nodes
+	tr := newTree(t, "/contacts/list", "/contact", "/con", "/cont/:id", "/")
+	for _, p := range []string{"/contacts/list", "/contact", "/con", "/"} {
+		if val, _, found := tr.Match(p, nil); !found || val != p {
+			t.Errorf("Match(%q) = %v, %v, want %q", p, val, found, p)
+		}
+	}
+	if val, params, _ := tr.Match("/cont/9", nil); val != "/cont/:id" || params[0].Value != "9" {
+		t.Errorf("Match(/cont/9) = %v, %v, want /cont/:id", val, params)
+	}
+	for _, p := range []string{"/contacts", "/co", "/contact/"} {
+		if val, _, found := tr.Match(p, nil); found {
+			t.Errorf("Match(%q) = %v, want no match", p, val)
+		}
+	}
+	if tr.Size() != 5 {
+		t.Errorf("Size() = %d, want 5", tr.Size())
+	}
+}
+
+func TestInsertRouteConflicts(t *testing.T) {
+	tests := []struct {
+		existing, pattern string
+	}{
+		{"/users/:id", "/users/:name"},
+		{"/users/:id/posts", "/users/:name"},
+		{"/files/*path", "/files/*rest"},
+		{"/users/:id<int>", "/users/:n<int>"},
+	}
+	for _, tt := range tests {
+		tr := newTree(t, tt.existing)
+		err := tr.InsertRoute(tt.pattern, tt.pattern)
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) {
+			t.Errorf("InsertRoute(%q) after %q = %v, want a ConflictError", tt.pattern, tt.existing, err)
+			continue
+		}
+		if conflict.Existing != tt.existing || conflict.Value != tt.existing {
+			t.Errorf("conflict = %+v, want existing %q", conflict, tt.existing)
+		}
+	}
+
+	for _, pattern := range []string{"", "/files/*", "/users/:", "/files/*path/x", "/a/:id<int"} {
+		if err := New().InsertRoute(pattern, nil); err == nil {
+			t.Errorf("InsertRoute(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestGetRoute(t *testing.T) {
+	tr := newTree(t, "/users/:id", "/files/*path")
+	for _, p := range []string{"/users/:id", "/files/*path"} {
+		if val, found := tr.GetRoute(p); !found || val != p {
+			t.Errorf("GetRoute(%q) = %v, %v, want %q", p, val, found, p)
+		}
+	}
+	// Patterns are looked up literally, not matched
+	for _, p := range []string{"/users/42", "/users/:name", "/users", "/files/*rest"} {
+		if val, found := tr.GetRoute(p); found {
+			t.Errorf("GetRoute(%q) = %v, want not found", p, val)
+		}
+	}
+}
+
+func TestDeleteRoute(t *testing.T) {
+	tr := newTree(t, "/users/:id", "/users/:id/posts")
+	if !tr.DeleteRoute("/users/:id") {
+		t.Fatal("DeleteRoute(/users/:id) = false, want true")
+	}
+	if tr.DeleteRoute("/users/:id") {
+		t.Error("second DeleteRoute(/users/:id) = true, want false")
+	}
+	if _, _, found := tr.Match("/users/1", nil); found {
+		t.Error("Match(/users/1) found a deleted route")
+	}
+	if val, _, _ := tr.Match("/users/1/posts", nil); val != "/users/:id/posts" {
+		t.Errorf("Match(/users/1/posts) = %v, want /users/:id/posts", val)
+	}
+	if tr.Size() != 1 {
+		t.Errorf("Size() = %d, want 1", tr.Size())
+	}
+
+	// Once no route is left below a wildcard, another name may take its place
+	tr.DeleteRoute("/users/:id/posts")
+	if err := tr.InsertRoute("/users/:name", "/users/:name"); err != nil {
+		t.Errorf("InsertRoute(/users/:name) after deleting /users/:id: %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	tr := newTree(t, "/users/:id", "/users/new", "/files/*path")
+	c := tr.Clone()
+
+	if err := c.InsertRoute("/users/:id/posts", "posts"); err != nil {
+		t.Fatal(err)
+	}
+	c.InsertRoute("/us", "us")
+	c.DeleteRoute("/users/new")
+	if err := tr.InsertRoute("/files/:name<int>", "int file"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tree  *Tree
+		path  string
+		value any // nil when nothing matches
+	}{
+		{tr, "/users/1/posts", nil},
+		{tr, "/us", nil},
+		{tr, "/users/new", "/users/new"},
+		{tr, "/files/7", "int file"},
+		{c, "/users/1/posts", "posts"},
+		{c, "/us", "us"},
+		{c, "/users/new", "/users/:id"},
+		{c, "/files/7", "/files/*path"},
+	}
+	for _, tt := range tests {
+		val, _, _ := tt.tree.Match(tt.path, nil)
+		if val != tt.value {
+			name := "original"
+			if tt.tree == c {
+				name = "clone"
+			}
+			t.Errorf("%s: Match(%q) = %v, want %v", name, tt.path, val, tt.value)
+		}
+	}
+	if tr.Size() != 4 || c.Size() != 4 {
+		t.Errorf("sizes = %d, %d, want 4, 4", tr.Size(), c.Size())
+	}
+}
+
+func TestMatchFold(t *testing.T) {
+	tr := newTree(t, "/Users/:id", "/about")
+	tests := []struct{ path, want string }{
+		{"/users/Bob", "/Users/Bob"},
+		{"/ABOUT", "/about"},
+	}
+	for _, tt := range tests {
+		got, found := tr.MatchFold(tt.path)
+		if !found || got != tt.want {
+			t.Errorf("MatchFold(%q) = %q, %v, want %q", tt.path, got, found, tt.want)
+		}
+	}
+	if _, found := tr.MatchFold("/contact"); found {
+		t.Error("MatchFold(/contact) found a route")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kashari/draupnir/tree"
@@ -48,18 +49,62 @@ const (
 	MIMEApplicationOctetStream = "application/octet-stream"
 )
 
-type ctxKey string
-
-const paramsKey ctxKey = "params"
-
 type route struct {
 	method  string
-	pattern string // e.g., "/users/:id"
-	handler http.HandlerFunc
+	host    string      // host pattern, e.g. ":tenant.example.com"; empty for any host
+	pattern string      // e.g., "/users/:id"
+	handler HandlerFunc // runs behind the global and group Context middleware
+	site    string      // file:line where the route was registered
+	name    string      // optional name used for reverse routing
+
+	handlerName string         // name of the user handler, for introspection
+	group       *RouterGroup   // group the route was registered on, nil for the router
+	meta        map[string]any // user metadata, for introspection
+	mount       string         // prefix of a Mount, reported instead of its patterns
+
+	middlewares []middlewareEntry // route middleware, run after the group middleware
+	maxBodySize int64             // request body limit, 0 to use the group's or the router's
+}
+
+// RouteInfo describes a registered route, as returned by Router.Routes.
+type RouteInfo struct {
+	Method      string         `json:"method"`
+	Host        string         `json:"host,omitempty"`
+	Pattern     string         `json:"pattern"`
+	Name        string         `json:"name,omitempty"`
+	Handler     string         `json:"handler"`
+	Middlewares []string       `json:"middlewares,omitempty"`
+	Group       string         `json:"group,omitempty"`
+	Meta        map[string]any `json:"meta,omitempty"`
+}
+
+// hostRoutes holds the routes of a virtual host, or of any host for the router's root.
+// The notFound and methodNotAllowed trees hold group handlers keyed by group prefix,
+// so that the group with the longest prefix of the request path wins.
+type hostRoutes struct {
+	pattern          string // e.g. "api.example.com" or ":tenant.example.com"; empty for the root
+	routes           *tree.Tree
+	notFound         *tree.Tree
+	methodNotAllowed *tree.Tree
+}
+
+// routeTable is a snapshot of the routes served by a Router.
+// A published table is never modified: registration clones the current table,
+// changes the copy and swaps it in, so ServeHTTP reads it without locking.
+type routeTable struct {
+	root  *hostRoutes   // routes for any host, one methodRoutes per pattern
+	hosts []*hostRoutes // routes restricted to a host, static hosts first
+	names *tree.Tree    // route name -> pattern, for reverse routing
 }
 
-// Wrapper for http.HandlerFunc
-type Middleware func(http.HandlerFunc) http.HandlerFunc
+// methodRoutes holds every route registered for a single path, keyed by HTTP method.
+// Like the table it belongs to, it is copied rather than modified once published.
+type methodRoutes map[string]route
+
+// Middleware runs before the route handler in the Context pipeline. It continues the
+// pipeline with Context.Next, or stops it with Context.Abort. Middleware of the standard
+// func(http.Handler) http.Handler shape is adapted with WrapMiddleware.
+type Middleware func(*Context) error
 
 type WorkerPool struct {
 	tasks chan func()
@@ -78,11 +123,27 @@ type RateLimiter struct {
 
 // Router is our HTTP router with integrated logging.
 type Router struct {
-	staticRoutes  *tree.Tree   // static routes stored by exact path
-	dynamicRoutes []route      // routes with parameters (e.g., ":id")
-	middlewares   []Middleware // middleware chain
-	workerPool    *WorkerPool  // optional worker pool for concurrent handling
-	rateLimiter   *RateLimiter // optional rate limiter on the critical path
+	table           atomic.Pointer[routeTable] // current routes, swapped on registration
+	mu              sync.Mutex                 // serializes registration
+	middlewares     middlewareChain            // global middleware
+	workerPool      *WorkerPool                // optional worker pool for concurrent handling
+	rateLimiter     *RateLimiter               // optional rate limiter on the critical path
+	strict          bool                       // panic on route registration errors instead of collecting them
+	errs            []error                    // route registration errors collected in lenient mode
+	autoHEAD        bool                       // serve HEAD from GET routes
+	autoOPTIONS     bool                       // answer OPTIONS with the Allow header
+	errorHandler    ErrorHandlerFunc           // renders errors returned by handlers, DefaultErrorHandler if nil
+	pool            sync.Pool                  // recycled request Contexts
+	requestLogging  bool                       // log every request with its duration
+	validator       *Validator                 // validates bound structs
+	codec           Codec                      // encodes and decodes JSON
+	maxBodySize     int64                      // request body limit of every route, 0 for none
+	multipartMemory int64                      // bytes of a multipart form kept in memory
+
+	redirectTrailingSlash bool // redirect /foo/ to /foo and vice versa when only that matches
+	redirectFixedPath     bool // redirect to the cleaned, case-corrected path when only that matches
+	useRawPath            bool // match against URL.RawPath when available
+	unescapePathValues    bool // unescape parameter values matched against the raw path
 }
 
 type Group struct {
@@ -92,10 +153,7 @@ type Group struct {
 }
 
 // Param represents a single URL parameter
-type Param struct {
-	Key   string
-	Value string
-}
+type Param = tree.Param
 
 // HandlerFunc defines a function to handle HTTP requests
 type HandlerFunc func(*Context) error
@@ -107,8 +165,12 @@ type Context struct {
 	method          string
 	handlers        []HandlerFunc
 	index           int
-	store           map[string]any
+	params          []Param
+	store           *valueStore // created by the first Set
+	storeMu         sync.Mutex
 	Writer          http.ResponseWriter
+	writer          responseWriter // the writer behind Writer, tracking whether the response started
+	pattern         string         // pattern of the matched route, empty for fallbacks
 	router          *Router
 	bodyParsed      bool
 	queryParsed     bool
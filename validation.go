@@ -0,0 +1,393 @@
+package draupnir
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidationFunc reports whether a field value satisfies a validation rule. Pointers are
+// dereferenced before the rule runs, and param is the text after "=" in the tag, such as
+// "3" in min=3.
+type ValidationFunc func(value reflect.Value, param string) bool
+
+// FieldError describes a field failing a validation rule.
+type FieldError struct {
+	Field   string // path of the field, e.g. "items[0].name"
+	Rule    string // name of the failed rule, e.g. "min"
+	Param   string // parameter of the rule, e.g. "3"
+	Message string // human readable description, e.g. "must be at least 3 characters"
+}
+
+// ValidationErrors lists the fields failing validation, one error per field.
+// The default error handler renders it as a 422 JSON response keyed by field.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	var b strings.Builder
+	b.WriteString("validation failed")
+	for i, fe := range e {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(fe.Field + " " + fe.Message)
+	}
+	return b.String()
+}
+
+// Fields returns the error message of each failing field, keyed by field path.
+func (e ValidationErrors) Fields() map[string]string {
+	fields := make(map[string]string, len(e))
+	for _, fe := range e {
+		fields[fe.Field] = fe.Message
+	}
+	return fields
+}
+
+// Validator checks structs against the rules in their `validate` tags, such as
+// `validate:"required,min=3,max=64"`. Nested structs, and the structs held by slices
+// and pointers, are validated too. The rules are:
+//
+//   - required: the field is not empty; a non-nil pointer is never empty
+//   - omitempty: skip the remaining rules when the field is empty
+//   - min=n, max=n, len=n: bounds on the length of strings (in characters), slices
+//     and maps, or on the value of numbers
+//   - email: a bare email address
+//   - oneof=a b c: one of the space separated values
+//
+// plus any rule added with RegisterValidation.
+type Validator struct {
+	mu    sync.RWMutex
+	rules map[string]ValidationFunc
+	cache sync.Map // reflect.Type -> []validatedField
+}
+
+// validatedField is a struct field with rules or with nested structs to validate.
+type validatedField struct {
+	index    int
+	name     string // name in error paths, from the json or form tag when present
+	embedded bool   // anonymous struct whose fields are promoted to the parent path
+	rules    []validationRule
+}
+
+type validationRule struct {
+	name  string
+	param string
+}
+
+// NewValidator returns a Validator with the built-in rules.
+func NewValidator() *Validator {
+	return &Validator{
+		rules: map[string]ValidationFunc{
+			"min":   validateMin,
+			"max":   validateMax,
+			"len":   validateLen,
+			"email": validateEmail,
+			"oneof": validateOneOf,
+		},
+	}
+}
+
+// RegisterValidation adds a rule to the validator, or replaces the rule of that name.
+// It panics when the name is empty, contains a comma or "=", or is required or omitempty.
+func (v *Validator) RegisterValidation(name string, fn ValidationFunc) {
+	if name == "" || strings.ContainsAny(name, ",=") || name == "required" || name == "omitempty" {
+		panic(fmt.Sprintf("draupnir: invalid validation rule name %q", name))
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[name] = fn
+}
+
+// RegisterValidation adds a rule to the validator used by the Bind methods.
+// See Validator.RegisterValidation.
+func (r *Router) RegisterValidation(name string, fn ValidationFunc) *Router {
+	r.validator.RegisterValidation(name, fn)
+	return r
+}
+
+// Validate checks obj against its `validate` tags with the router's validator.
+// The Bind methods call it once obj is decoded.
+func (c *Context) Validate(obj any) error {
+	return c.router.validator.Validate(obj)
+}
+
+// Validate checks obj, a struct or a pointer to, or slice of, structs. It returns
+// ValidationErrors when fields fail their rules, and an error when a tag names an
+// unknown rule.
+func (v *Validator) Validate(obj any) error {
+	var errs ValidationErrors
+	if err := v.validate(reflect.ValueOf(obj), "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *Validator) validate(value reflect.Value, path string, errs *ValidationErrors) error {
+	value = indirect(value)
+	switch value.Kind() {
+	case reflect.Struct:
+		return v.validateStruct(value, path, errs)
+	case reflect.Slice, reflect.Array:
+		if !canNest(value.Type().Elem()) {
+			return nil
+		}
+		for i := range value.Len() {
+			if err := v.validate(value.Index(i), path+"["+strconv.Itoa(i)+"]", errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateStruct(value reflect.Value, prefix string, errs *ValidationErrors) error {
+	for _, f := range v.fields(value.Type()) {
+		field := value.Field(f.index)
+		if f.embedded {
+			if err := v.validate(field, prefix, errs); err != nil {
+				return err
+			}
+			continue
+		}
+
+		path := f.name
+		if prefix != "" {
+			path = prefix + "." + f.name
+		}
+		failed, err := v.check(field, path, f.rules, errs)
+		if err != nil {
+			return err
+		}
+		if !failed {
+			if err := v.validate(field, path, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check applies rules to a field in order, recording the first one failing.
+func (v *Validator) check(field reflect.Value, path string, rules []validationRule, errs *ValidationErrors) (bool, error) {
+	value := indirect(field)
+	for _, rule := range rules {
+		switch rule.name {
+		case "omitempty":
+			if isEmpty(field) {
+				return false, nil
+			}
+			continue
+		case "required":
+			if isEmpty(field) {
+				*errs = append(*errs, FieldError{Field: path, Rule: rule.name, Message: "is required"})
+				return true, nil
+			}
+			continue
+		}
+
+		v.mu.RLock()
+		fn, ok := v.rules[rule.name]
+		v.mu.RUnlock()
+		if !ok {
+			return false, fmt.Errorf("draupnir: unknown validation rule %q on field %s", rule.name, path)
+		}
+		if value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+			// A nil pointer is only checked by required.
+			return false, nil
+		}
+		if !fn(value, rule.param) {
+			*errs = append(*errs, FieldError{
+				Field:   path,
+				Rule:    rule.name,
+				Param:   rule.param,
+				Message: validationMessage(value, rule),
+			})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fields returns the fields of a struct type to validate, parsing their tags on first use.
+func (v *Validator) fields(t reflect.Type) []validatedField {
+	if fields, ok := v.cache.Load(t); ok {
+		return fields.([]validatedField)
+	}
+
+	var fields []validatedField
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "-" || !sf.IsExported() && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		name, named := validationName(sf)
+		f := validatedField{index: i, name: name, embedded: sf.Anonymous && !named && indirectType(sf.Type).Kind() == reflect.Struct}
+		for _, rule := range strings.Split(tag, ",") {
+			if rule = strings.TrimSpace(rule); rule != "" {
+				name, param, _ := strings.Cut(rule, "=")
+				f.rules = append(f.rules, validationRule{name: name, param: param})
+			}
+		}
+		if len(f.rules) > 0 || canNest(sf.Type) {
+			fields = append(fields, f)
+		}
+	}
+
+	actual, _ := v.cache.LoadOrStore(t, fields)
+	return actual.([]validatedField)
+}
+
+// validationName returns the name of a field in error paths: the name in its json tag,
+// or in one of the binding tags, or the field name.
+func validationName(sf reflect.StructField) (string, bool) {
+	for _, tag := range []string{"json", "form", "query", "uri", "header", "xml"} {
+		name, _, _ := strings.Cut(sf.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name, true
+		}
+	}
+	return sf.Name, false
+}
+
+// canNest reports whether values of t may hold structs to validate.
+func canNest(t reflect.Type) bool {
+	t = indirectType(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Slice, reflect.Array:
+		return canNest(t.Elem())
+	}
+	return false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// size returns the length of strings, in characters, and of collections, or the value
+// of numbers. ok is false for other kinds.
+func size(v reflect.Value) (n float64, ok bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compareSize(v reflect.Value, param string, cmp func(n, bound float64) bool) bool {
+	bound, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false
+	}
+	n, ok := size(v)
+	return ok && cmp(n, bound)
+}
+
+func validateMin(v reflect.Value, param string) bool {
+	return compareSize(v, param, func(n, bound float64) bool { return n >= bound })
+}
+
+func validateMax(v reflect.Value, param string) bool {
+	return compareSize(v, param, func(n, bound float64) bool { return n <= bound })
+}
+
+func validateLen(v reflect.Value, param string) bool {
+	return compareSize(v, param, func(n, bound float64) bool { return n == bound })
+}
+
+func validateEmail(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(v.String())
+	return err == nil && addr.Address == v.String()
+}
+
+func validateOneOf(v reflect.Value, param string) bool {
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(v.Uint(), 10)
+	default:
+		return false
+	}
+	for _, option := range strings.Fields(param) {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validationMessage describes a failed rule for the kind of value it checked.
+func validationMessage(v reflect.Value, rule validationRule) string {
+	unit := ""
+	switch v.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		unit = " items"
+	}
+	switch rule.name {
+	case "min":
+		return "must be at least " + rule.param + unit
+	case "max":
+		return "must be at most " + rule.param + unit
+	case "len":
+		if unit == "" {
+			return "must be " + rule.param
+		}
+		return "must be exactly " + rule.param + unit
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(rule.param), ", ")
+	}
+	return "does not satisfy " + rule.name
+}
@@ -0,0 +1,233 @@
+package draupnir
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fieldRules returns "field:rule" for each error of a validation, in order.
+func fieldRules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var ve ValidationErrors
+	if !errors.As(err, &ve) {
+		t.Fatalf("Validate = %v, want ValidationErrors", err)
+	}
+	var got []string
+	for _, fe := range ve {
+		got = append(got, fe.Field+":"+fe.Rule)
+	}
+	return got
+}
+
+func TestValidateRules(t *testing.T) {
+	type rules struct {
+		Name    string   `validate:"min=2,max=4"`
+		Code    string   `validate:"len=3"`
+		Age     int      `validate:"min=18,max=130"`
+		Score   float64  `validate:"max=1.5"`
+		Count   uint     `validate:"len=2"`
+		Tags    []string `validate:"min=1,max=2"`
+		Email   string   `validate:"email"`
+		Role    string   `validate:"oneof=admin user"`
+		Level   int      `validate:"oneof=1 2 3"`
+		Unicode string   `validate:"max=3"`
+	}
+	valid := rules{Name: "bob", Code: "abc", Age: 30, Score: 1.5, Count: 2, Tags: []string{"a"}, Email: "bob@example.com", Role: "user", Level: 2, Unicode: "日本語"}
+
+	tests := []struct {
+		change func(*rules)
+		want   string
+	}{
+		{func(r *rules) { r.Name = "b" }, "Name:min"},
+		{func(r *rules) { r.Name = "bobby" }, "Name:max"},
+		{func(r *rules) { r.Code = "ab" }, "Code:len"},
+		{func(r *rules) { r.Age = 17 }, "Age:min"},
+		{func(r *rules) { r.Age = 131 }, "Age:max"},
+		{func(r *rules) { r.Score = 1.6 }, "Score:max"},
+		{func(r *rules) { r.Count = 3 }, "Count:len"},
+		{func(r *rules) { r.Tags = nil }, "Tags:min"},
+		{func(r *rules) { r.Tags = []string{"a", "b", "c"} }, "Tags:max"},
+		{func(r *rules) { r.Email = "bob" }, "Email:email"},
+		{func(r *rules) { r.Email = "Bob <bob@example.com>" }, "Email:email"},
+		{func(r *rules) { r.Role = "root" }, "Role:oneof"},
+		{func(r *rules) { r.Level = 4 }, "Level:oneof"},
+		{func(r *rules) { r.Unicode = "日本語!" }, "Unicode:max"},
+	}
+
+	v := NewValidator()
+	if err := v.Validate(valid); err != nil {
+		t.Fatalf("Validate(valid) = %v", err)
+	}
+	for _, tt := range tests {
+		r := valid
+		tt.change(&r)
+		got := fieldRules(t, v.Validate(&r))
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("Validate = %q, want [%s]", got, tt.want)
+		}
+	}
+}
+
+func TestValidateRequiredAndOmitEmpty(t *testing.T) {
+	type form struct {
+		Name     string  `validate:"required"`
+		Nick     string  `validate:"omitempty,min=3"`
+		Age      *int    `validate:"required,min=18"`
+		Zero     *int    `validate:"required"`
+		Optional *string `validate:"omitempty,email"`
+		Ignored  *string `validate:"email"`
+	}
+
+	zero := 0
+	got := fieldRules(t, NewValidator().Validate(form{Zero: &zero}))
+	if want := []string{"Name:required", "Age:required"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate(empty) = %q, want %q", got, want)
+	}
+
+	age := 17
+	nick := "x"
+	got = fieldRules(t, NewValidator().Validate(form{Name: "a", Nick: nick, Age: &age, Zero: &zero}))
+	if want := []string{"Nick:min", "Age:min"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate = %q, want %q", got, want)
+	}
+
+	bad := "not an email"
+	got = fieldRules(t, NewValidator().Validate(form{Name: "a", Age: new(int), Zero: &zero, Optional: &bad}))
+	if want := []string{"Age:min", "Optional:email"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate = %q, want %q", got, want)
+	}
+}
+
+func TestValidateNestedPaths(t *testing.T) {
+	type item struct {
+		Name string `json:"name" validate:"required"`
+	}
+	type address struct {
+		City string `validate:"required"`
+	}
+	type Base struct {
+		ID int `validate:"min=1"`
+	}
+	type order struct {
+		Base
+		Items    []item   `json:"items" validate:"min=1"`
+		Ptrs     []*item  `form:"ptrs"`
+		Address  address  `json:"address"`
+		Billing  *address `json:"billing"`
+		Shipping *address `json:"shipping" validate:"required"`
+	}
+
+	got := fieldRules(t, NewValidator().Validate(&order{
+		Items:   []item{{Name: "a"}, {}},
+		Ptrs:    []*item{nil, {}},
+		Billing: &address{},
+	}))
+	want := []string{"ID:min", "items[1].name:required", "ptrs[1].name:required", "address.City:required", "billing.City:required", "shipping:required"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate = %q, want %q", got, want)
+	}
+
+	// Nested values are not validated once their own rules fail
+	got = fieldRules(t, NewValidator().Validate(order{Base: Base{ID: 1}, Address: address{City: "x"}, Shipping: &address{City: "x"}}))
+	if want := []string{"items:min"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate = %q, want %q", got, want)
+	}
+
+	got = fieldRules(t, NewValidator().Validate([]item{{Name: "a"}, {}}))
+	if want := []string{"[1].name:required"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate(slice) = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	type account struct {
+		Handle string `validate:"required,lowercase"`
+		Port   int    `validate:"between=1024 65535"`
+	}
+
+	v := NewValidator()
+	v.RegisterValidation("lowercase", func(value reflect.Value, _ string) bool {
+		return value.String() == strings.ToLower(value.String())
+	})
+	v.RegisterValidation("between", func(value reflect.Value, param string) bool {
+		lo, hi, _ := strings.Cut(param, " ")
+		return validateMin(value, lo) && validateMax(value, hi)
+	})
+
+	got := fieldRules(t, v.Validate(account{Handle: "Bob", Port: 80}))
+	if want := []string{"Handle:lowercase", "Port:between"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate = %q, want %q", got, want)
+	}
+	var ve ValidationErrors
+	errors.As(v.Validate(account{Handle: "Bob", Port: 2000}), &ve)
+	if len(ve) != 1 || ve[0].Message != "does not satisfy lowercase" {
+		t.Errorf("errors = %+v, want the generic message", ve)
+	}
+	if err := v.Validate(account{Handle: "bob", Port: 8080}); err != nil {
+		t.Errorf("Validate(valid) = %v", err)
+	}
+
+	for _, name := range []string{"", "a,b", "a=b", "required", "omitempty"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterValidation(%q) did not panic", name)
+				}
+			}()
+			v.RegisterValidation(name, nil)
+		}()
+	}
+}
+
+func TestValidateUnknownRule(t *testing.T) {
+	type typo struct {
+		Name string `validate:"requird"`
+	}
+	err := NewValidator().Validate(typo{Name: "a"})
+	var ve ValidationErrors
+	if err == nil || errors.As(err, &ve) || !strings.Contains(err.Error(), `unknown validation rule "requird" on field Name`) {
+		t.Errorf("Validate = %v, want an unknown rule error", err)
+	}
+}
+
+func TestValidationErrorResponse(t *testing.T) {
+	type signup struct {
+		Name  string `json:"name" validate:"required"`
+		Email string `json:"email" validate:"email"`
+		Age   int    `json:"age" validate:"min=18"`
+	}
+
+	r := New().WithRequestLogging(false)
+	r.POST("/signup", func(c *Context) error {
+		var s signup
+		if err := c.Bind(&s); err != nil {
+			return err
+		}
+		return c.String(http.StatusCreated, "created")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, bodyRequest(http.MethodPost, "/signup", MIMEApplicationJSON, `{"email":"nope","age":12}`))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+	want := `{"error":"validation failed","fields":{"age":"must be at least 18","email":"must be a valid email address","name":"is required"}}`
+	if w.Body.String() != want {
+		t.Errorf("body = %s, want %s", w.Body.String(), want)
+	}
+	if ct := w.Header().Get(HeaderContentType); !strings.HasPrefix(ct, MIMEApplicationJSON) {
+		t.Errorf("Content-Type = %q, want JSON", ct)
+	}
+
+	req := bodyRequest(http.MethodPost, "/signup", MIMEApplicationJSON, `{"name":"bob","email":"bob@example.com","age":18}`)
+	if code := bindStatus(t, req, "/signup", func(c *Context) error { var s signup; return c.Bind(&s) }); code != http.StatusOK {
+		t.Errorf("valid signup: status = %d, want 200", code)
+	}
+}
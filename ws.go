@@ -215,19 +215,17 @@ func (w *WebSocketConn) Header(s string) any {
 }
 
 // WEBSOCKET adds a WebSocket endpoint to the router
-func (r *Router) WEBSOCKET(pattern string, handler WebSocketHandler) *Router {
-	return r.HandleFunc("GET", pattern, func(c *Context) {
+func (r *Router) WEBSOCKET(pattern string, handler WebSocketHandler, middlewares ...Middleware) *Route {
+	return r.HandleFunc("GET", pattern, func(c *Context) error {
 		// Check if the request is a WebSocket upgrade request
 		if !isWebSocketUpgrade(c.Request) {
-			http.Error(c.Writer, "Not a WebSocket handshake", http.StatusBadRequest)
-			return
+			return NewHTTPError(http.StatusBadRequest, "Not a WebSocket handshake")
 		}
 
 		// Create a new WebSocket connection
 		wsConn, err := upgradeToWebSocket(c.Writer, c.Request)
 		if err != nil {
-			http.Error(c.Writer, "Could not upgrade to WebSocket", http.StatusInternalServerError)
-			return
+			return NewHTTPError(http.StatusInternalServerError, "Could not upgrade to WebSocket").WithCause(err)
 		}
 
 		// Create our WebSocketConn wrapper
@@ -244,7 +242,8 @@ func (r *Router) WEBSOCKET(pattern string, handler WebSocketHandler) *Router {
 
 		// Call the handler
 		handler(conn)
-	})
+		return nil
+	}, middlewares...)
 }
 
 // isWebSocketUpgrade checks if the request is a WebSocket upgrade request
@@ -5,6 +5,28 @@ import (
 	"io"
 )
 
+// Encoder writes JSON values to a stream.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads JSON values from a stream.
+type Decoder interface {
+	Decode(v any) error
+}
+
+// JSONCodec creates the encoders and decoders used by WriteJSON and ReadJSON.
+type JSONCodec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// SetJSONCodec sets the codec used by WriteJSON and ReadJSON. By default, and when
+// codec is nil, messages are encoded with encoding/json.
+func (c *Conn) SetJSONCodec(codec JSONCodec) {
+	c.jsonCodec = codec
+}
+
 // WriteJSON writes the JSON encoding of v as a message.
 //
 // See the documentation for encoding/json Marshal for details about the
@@ -14,7 +36,13 @@ func (c *Conn) WriteJSON(v any) error {
 	if err != nil {
 		return err
 	}
-	err1 := json.NewEncoder(w).Encode(v)
+	var enc Encoder
+	if c.jsonCodec != nil {
+		enc = c.jsonCodec.NewEncoder(w)
+	} else {
+		enc = json.NewEncoder(w)
+	}
+	err1 := enc.Encode(v)
 	err2 := w.Close()
 	if err1 != nil {
 		return err1
@@ -32,7 +60,13 @@ func (c *Conn) ReadJSON(v interface{}) error {
 	if err != nil {
 		return err
 	}
-	err = json.NewDecoder(r).Decode(v)
+	var dec Decoder
+	if c.jsonCodec != nil {
+		dec = c.jsonCodec.NewDecoder(r)
+	} else {
+		dec = json.NewDecoder(r)
+	}
+	err = dec.Decode(v)
 	if err == io.EOF {
 		// One value is expected in the message.
 		err = io.ErrUnexpectedEOF